@@ -45,6 +45,27 @@ func (p *Plugin) EnsureBadges() {
 			ImageType:   badgesmodel.ImageTypeEmoji,
 			Multiple:    false,
 		},
+		{
+			Name:        AchievementNameDaily,
+			Description: "Clear the daily memory challenge 3 days in a row",
+			Image:       "date",
+			ImageType:   badgesmodel.ImageTypeEmoji,
+			Multiple:    false,
+		},
+		{
+			Name:        AchievementNameWeekly,
+			Description: "Clear the daily memory challenge 7 days in a row",
+			Image:       "calendar",
+			ImageType:   badgesmodel.ImageTypeEmoji,
+			Multiple:    false,
+		},
+		{
+			Name:        AchievementNameMonthly,
+			Description: "Clear the daily memory challenge 30 days in a row",
+			Image:       "fire",
+			ImageType:   badgesmodel.ImageTypeEmoji,
+			Multiple:    false,
+		},
 	}
 
 	reqBody := badgesmodel.EnsureBadgesRequest{
@@ -77,19 +98,37 @@ func (p *Plugin) EnsureBadges() {
 		return
 	}
 
-	p.badgesMap = map[string]badgesmodel.BadgeID{}
+	badgesMap := map[string]badgesmodel.BadgeID{}
 	for _, badge := range newBadges {
-		p.badgesMap[badge.Name] = badge.ID
+		badgesMap[badge.Name] = badge.ID
 	}
+
+	p.badgesLock.Lock()
+	p.badgesMap = badgesMap
+	p.badgesLock.Unlock()
+}
+
+// getBadgesMap returns the IDs of the badges by name, or nil if they were not
+// ensured yet.
+func (p *Plugin) getBadgesMap() map[string]badgesmodel.BadgeID {
+	p.badgesLock.RLock()
+	defer p.badgesLock.RUnlock()
+
+	return p.badgesMap
 }
 
 func (p *Plugin) GrantBadge(name string, userID string) {
-	if p.badgesMap == nil {
+	if !p.getConfiguration().EnableBadges {
+		return
+	}
+
+	badgesMap := p.getBadgesMap()
+	if badgesMap == nil {
 		p.API.LogDebug("No badges map")
 		return
 	}
 
-	badgeID, ok := p.badgesMap[name]
+	badgeID, ok := badgesMap[name]
 	if !ok {
 		p.API.LogDebug("Achievement not recognized")
 		return
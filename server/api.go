@@ -2,10 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"runtime/debug"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -26,12 +30,54 @@ const (
 	ResponseTypeDialog ResponseType = "DIALOG"
 )
 
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
 type APIErrorResponse struct {
 	ID         string `json:"id"`
 	Message    string `json:"message"`
 	StatusCode int    `json:"status_code"`
 }
 
+// IDs of the errors returned by the API. They are stable, so clients can
+// rely on them instead of on messages.
+const (
+	ErrorIDInternal            = "internal_error"
+	ErrorIDNotAuthorized       = "not_authorized"
+	ErrorIDForbidden           = "forbidden"
+	ErrorIDNotFound            = "not_found"
+	ErrorIDInvalidRequest      = "invalid_request"
+	ErrorIDInvalidParameter    = "invalid_parameter"
+	ErrorIDCannotPing          = "cannot_ping"
+	ErrorIDStaleMove           = "stale_move"
+	ErrorIDNotOwner            = "not_owner"
+	ErrorIDNotAPlayer          = "not_a_player"
+	ErrorIDChannelTypeDisabled = "channel_type_disabled"
+	ErrorIDGameNotFound        = "game_not_found"
+	ErrorIDIndexOutOfRange     = "index_out_of_range"
+	ErrorIDCardAlreadyFlipped  = "card_already_flipped"
+	ErrorIDNotYourTurn         = "not_your_turn"
+	ErrorIDGameOver            = "game_over"
+	ErrorIDGameStarted         = "game_started"
+	ErrorIDGameNotStarted      = "game_not_started"
+	ErrorIDAlreadyJoined       = "already_joined"
+	ErrorIDGameFull            = "game_full"
+	ErrorIDNotEnoughPlayers    = "not_enough_players"
+	ErrorIDTooManyGames        = "too_many_games"
+	ErrorIDDrawOffered         = "draw_already_offered"
+	ErrorIDNoDrawOffer         = "no_draw_offer"
+	ErrorIDGameNotFinished     = "game_not_finished"
+	ErrorIDRematchPlayed       = "rematch_already_played"
+	ErrorIDDailyPlayed         = "daily_challenge_already_played"
+	ErrorIDNoSeed              = "no_seed"
+	ErrorIDSeedHidden          = "seed_not_revealed"
+	ErrorIDAlreadyQueued       = "already_queued"
+	ErrorIDNotQueued           = "not_queued"
+	ErrorIDNotTeamMember       = "not_team_member"
+)
+
 func (p *Plugin) initializeAPI(staticAssets fs.FS) {
 	p.router = mux.NewRouter()
 	p.router.Use(p.withRecovery)
@@ -39,9 +85,35 @@ func (p *Plugin) initializeAPI(staticAssets fs.FS) {
 	apiRouter := p.router.PathPrefix("/api/v1").Subrouter()
 
 	apiRouter.HandleFunc("/game/{gameID}/flip", p.extractUserMiddleWare(p.handleFlipCard, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/resign", p.extractUserMiddleWare(p.handleResignGame, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/offer-draw", p.extractUserMiddleWare(p.handleOfferDraw, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/accept-draw", p.extractUserMiddleWare(p.handleAcceptDraw, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/join", p.extractUserMiddleWare(p.handleJoinGame, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/leave", p.extractUserMiddleWare(p.handleLeaveGame, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/rematch", p.extractUserMiddleWare(p.handleRematchGame, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/rematch/action", p.extractUserMiddleWare(p.handleRematchAction, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/begin", p.extractUserMiddleWare(p.handleBeginGame, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/game/{gameID}/snapshot.png", p.extractUserMiddleWare(p.handleGetSnapshot, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/game/{gameID}/board", p.extractUserMiddleWare(p.handleGetBoard, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/game/{gameID}/board/flip", p.extractUserMiddleWare(p.handleBoardFlip, ResponseTypeJSON)).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/game/{gameID}/ping", p.extractUserMiddleWare(p.handlePing, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/game/{gameID}/replay", p.extractUserMiddleWare(p.handleGetReplay, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/game/{gameID}/verify", p.extractUserMiddleWare(p.handleVerifyGame, ResponseTypeJSON)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/game/{gameID}", p.extractUserMiddleWare(p.handleGetGame, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/channel/{channelID}/games", p.extractUserMiddleWare(p.handleGetChannelGames, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/stats/{userID}", p.extractUserMiddleWare(p.handleGetStats, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/ratings/{userID}", p.extractUserMiddleWare(p.handleGetRating, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/leaderboard", p.extractUserMiddleWare(p.handleGetLeaderboard, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/solo/stats/{userID}", p.extractUserMiddleWare(p.handleGetSoloStats, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/solo/leaderboard", p.extractUserMiddleWare(p.handleGetSoloLeaderboard, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/daily", p.extractUserMiddleWare(p.handleGetDailyChallenge, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/daily", p.extractUserMiddleWare(p.handleStartDailyChallenge, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/matchmaking/join", p.extractUserMiddleWare(p.handleJoinMatchmaking, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/matchmaking/leave", p.extractUserMiddleWare(p.handleLeaveMatchmaking, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/history", p.extractUserMiddleWare(p.handleGetHistory, ResponseTypeJSON)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/start", p.extractUserMiddleWare(p.handleStartGame, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/invitation/{invitationID}/accept", p.extractUserMiddleWare(p.handleAcceptInvitation, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/invitation/{invitationID}/decline", p.extractUserMiddleWare(p.handleDeclineInvitation, ResponseTypeJSON)).Methods(http.MethodPost)
 
 	// Static files
 	p.router.PathPrefix("/static").Handler(http.StripPrefix("/", http.FileServer(http.FS(staticAssets))))
@@ -51,7 +123,7 @@ func (p *Plugin) initializeAPI(staticAssets fs.FS) {
 
 func (p *Plugin) defaultHandler(w http.ResponseWriter, r *http.Request) {
 	p.mm.Log.Debug("Unexpected call", "url", r.URL)
-	w.WriteHeader(http.StatusNotFound)
+	p.writeError(w, http.StatusNotFound, ErrorIDNotFound, "Not found.")
 }
 
 func dialogError(w http.ResponseWriter, text string, errors map[string]string) {
@@ -66,34 +138,32 @@ func (p *Plugin) handlePing(w http.ResponseWriter, r *http.Request, actingUserID
 	gameID, ok := mux.Vars(r)["gameID"]
 	if !ok {
 		p.mm.Log.Debug("No gameID")
-		w.WriteHeader(http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
 		return
 	}
 
 	game, err := p.getGame(gameID)
 	if err != nil {
 		p.mm.Log.Debug("cannot get game", "err", err)
-		w.WriteHeader(http.StatusBadRequest)
+		p.writeGameError(w, err)
 		return
 	}
 
-	if game.OtherPlayer != actingUserID {
+	if !game.IsPlayer(actingUserID) || game.CurrentPlayer() == actingUserID {
 		p.mm.Log.Debug("Wrong player")
 		p.sendResyncWebsocket(actingUserID, game)
-		w.WriteHeader(http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, ErrorIDCannotPing, "Only players waiting for their turn can ping the current player.")
 		return
 	}
 
 	u, err := p.mm.User.Get(actingUserID)
 	if err != nil {
 		p.mm.Log.Debug("Cannot get user")
-		w.WriteHeader(http.StatusInternalServerError)
+		p.writeInternalError(w)
 		return
 	}
 
-	_ = p.mm.Post.DM(p.BotUserID, game.CurrentPlayer, &model.Post{
-		Message: fmt.Sprintf("@%s is waiting for you to move.", u.Username),
-	})
+	p.notify(game.CurrentPlayer(), fmt.Sprintf("@%s is waiting for you to move.", u.Username))
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -102,7 +172,7 @@ func (p *Plugin) handleFlipCard(w http.ResponseWriter, r *http.Request, actingUs
 	gameID, ok := mux.Vars(r)["gameID"]
 	if !ok {
 		p.mm.Log.Debug("No gameID")
-		w.WriteHeader(http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
 		return
 	}
 
@@ -111,115 +181,189 @@ func (p *Plugin) handleFlipCard(w http.ResponseWriter, r *http.Request, actingUs
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		p.mm.Log.Debug("Cannot decode", "err", err)
-		w.WriteHeader(http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidRequest, "Cannot decode the request body.")
 		return
 	}
 
-	game, err := p.getGame(gameID)
+	game, value, err := p.flipCard(gameID, actingUserID, req.Index, req.Seq)
 	if err != nil {
-		p.mm.Log.Debug("cannot get game", "err", err)
-		w.WriteHeader(http.StatusBadRequest)
+		p.mm.Log.Debug("Cannot flip card", "err", err)
+		if current, getErr := p.getGame(gameID); getErr == nil {
+			p.sendResyncWebsocket(actingUserID, current)
+		}
+		p.writeGameError(w, err)
 		return
 	}
 
-	if game.CardFlipped[req.Index] {
-		p.mm.Log.Debug("Already flipped")
-		p.sendResyncWebsocket(actingUserID, game)
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	resp := FlipCardResponse{
+		Value:         value,
+		Seq:           game.Seq,
+		Turn:          game.CurrentPlayer() == actingUserID,
+		CurrentPlayer: p.getUsername(game.CurrentPlayer()),
+		Scores:        getScores(game),
 	}
 
-	if game.CurrentPlayer != actingUserID {
-		p.mm.Log.Debug("Wrong player")
-		p.sendResyncWebsocket(actingUserID, game)
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
+	p.writeJSON(w, resp)
 
-	otherPlayerID := game.OtherPlayer
+	p.sendFlipWebsocket(game, actingUserID, req.Index, value)
+	p.startBotTurn(game)
+}
 
-	game.CardFlipped[req.Index] = true
-	value := game.CardValues[req.Index]
+// handleBoardFlip answers the post action of a card button of a board post.
+// Errors are shown to the acting user only, and the board post is refreshed
+// by the move itself.
+func (p *Plugin) handleBoardFlip(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	gameID, ok := mux.Vars(r)["gameID"]
+	if !ok {
+		p.mm.Log.Debug("No gameID")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
+		return
+	}
 
-	if game.LastFlipped == -1 {
-		game.LastFlipped = req.Index
-	} else {
-		lastFlippedValue := game.CardValues[game.LastFlipped]
-		if lastFlippedValue == value {
-			game.Scores[actingUserID]++
-			game.Streak++
-		} else {
-			game.CardFlipped[game.LastFlipped] = false
-			game.CardFlipped[req.Index] = false
-			game.CurrentPlayer, game.OtherPlayer = game.OtherPlayer, game.CurrentPlayer
-			game.Streak = 0
-		}
-		game.LastFlipped = -1
+	req := model.PostActionIntegrationRequestFromJson(r.Body)
+	if req == nil {
+		p.mm.Log.Debug("Cannot decode post action")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidRequest, "Cannot decode the post action.")
+		return
 	}
 
-	if game.Streak >= 4 {
-		p.GrantBadge(AchievementNameStreak, game.CurrentPlayer)
+	index, indexOK := req.Context["index"].(float64)
+	seq, seqOK := req.Context["seq"].(float64)
+	if !indexOK || !seqOK {
+		p.mm.Log.Debug("Wrong post action context", "context", req.Context)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidRequest, "Missing card in the post action.")
+		return
 	}
 
-	finished := true
-	for _, flipped := range game.CardFlipped {
-		if !flipped {
-			finished = false
-			break
-		}
+	resp := &model.PostActionIntegrationResponse{}
+	game, value, err := p.flipCard(gameID, actingUserID, int(index), int(seq))
+	if err != nil {
+		p.mm.Log.Debug("Cannot flip card", "err", err)
+		resp.EphemeralText = gameErrorMessage(err, "Cannot flip the card.")
+		_, _ = w.Write(resp.ToJson())
+		return
 	}
 
-	if finished {
-		winner := game.CurrentPlayer
-		if game.Scores[game.CurrentPlayer] < game.Scores[game.OtherPlayer] {
-			winner = game.OtherPlayer
-		}
-		var stats *PlayerStats
-		stats, err = p.getPlayerStats(winner)
-		if err == nil {
-			stats.Wins++
-			if stats.Wins >= 1 {
-				p.GrantBadge(AchievementNameWinOne, winner)
-			}
-			if stats.Wins >= 5 {
-				p.GrantBadge(AchievementNameWinFive, winner)
-			}
-			if stats.Wins >= 10 {
-				p.GrantBadge(AchievementNameWinTen, winner)
-			}
-			_ = p.setPlayerStats(winner, stats)
+	_, _ = w.Write(resp.ToJson())
+
+	p.sendFlipWebsocket(game, actingUserID, int(index), value)
+	p.startBotTurn(game)
+}
+
+// sendFlipWebsocket tells every player but actingUserID about the card they
+// flipped.
+func (p *Plugin) sendFlipWebsocket(game *Game, actingUserID string, index int, value string) {
+	currentPlayer := p.getUsername(game.CurrentPlayer())
+	scores := getScores(game)
+	for _, player := range game.Players {
+		if player == actingUserID {
+			continue
 		}
-		p.GrantBadge(AchievementNamePlayOnce, game.CurrentPlayer)
-		p.GrantBadge(AchievementNamePlayOnce, game.OtherPlayer)
-		_ = p.removeGame(game)
-	} else {
-		err = p.setGame(game)
+		p.mm.Frontend.PublishWebSocketEvent("flip", map[string]interface{}{
+			"index":         index,
+			"value":         value,
+			"gID":           game.GID,
+			"seq":           game.Seq,
+			"turn":          game.CurrentPlayer() == player,
+			"currentPlayer": currentPlayer,
+			"scores":        scores,
+		}, &model.WebsocketBroadcast{UserId: player})
 	}
+}
 
-	if err != nil {
-		p.mm.Log.Debug("Cannot set game", "err", err)
-		p.sendResyncWebsocket(actingUserID, game)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+// gameErrors maps the errors returned while acting on games to the errors
+// sent to clients.
+var gameErrors = []struct {
+	err        error
+	id         string
+	message    string
+	statusCode int
+}{
+	{ErrStaleMove, ErrorIDStaleMove, "The game changed since your last move.", http.StatusConflict},
+	{ErrNotOwner, ErrorIDNotOwner, "Only the owner of the game can do this.", http.StatusForbidden},
+	{ErrNotAPlayer, ErrorIDNotAPlayer, "You are not a player of this game.", http.StatusForbidden},
+	{ErrChannelTypeDisabled, ErrorIDChannelTypeDisabled, "Games are disabled in this type of channel.", http.StatusForbidden},
+	{ErrGameNotFound, ErrorIDGameNotFound, "Game not found.", http.StatusNotFound},
+	{ErrIndexOutOfRange, ErrorIDIndexOutOfRange, "There is no card at this index.", http.StatusBadRequest},
+	{ErrAlreadyFlipped, ErrorIDCardAlreadyFlipped, "This card is already flipped.", http.StatusBadRequest},
+	{ErrNotYourTurn, ErrorIDNotYourTurn, "It is not your turn.", http.StatusBadRequest},
+	{ErrGameOver, ErrorIDGameOver, "The game is over.", http.StatusBadRequest},
+	{ErrGameStarted, ErrorIDGameStarted, "The game already started.", http.StatusBadRequest},
+	{ErrGameNotStarted, ErrorIDGameNotStarted, "The game has not started yet.", http.StatusBadRequest},
+	{ErrAlreadyJoined, ErrorIDAlreadyJoined, "You already joined this game.", http.StatusBadRequest},
+	{ErrGameFull, ErrorIDGameFull, "The game is full.", http.StatusBadRequest},
+	{ErrNotEnoughPlayers, ErrorIDNotEnoughPlayers, "There are not enough players.", http.StatusBadRequest},
+	{ErrTooManyGames, ErrorIDTooManyGames, "Too many unfinished games.", http.StatusBadRequest},
+	{ErrDrawOffered, ErrorIDDrawOffered, "You already offered a draw.", http.StatusBadRequest},
+	{ErrNoDrawOffer, ErrorIDNoDrawOffer, "There is no draw offer to accept.", http.StatusBadRequest},
+	{ErrGameNotFinished, ErrorIDGameNotFinished, "The game is not finished.", http.StatusBadRequest},
+	{ErrRematchPlayed, ErrorIDRematchPlayed, "The rematch of this game was already played.", http.StatusBadRequest},
+	{ErrDailyPlayed, ErrorIDDailyPlayed, "You already played today's challenge.", http.StatusBadRequest},
+	{ErrNoSeed, ErrorIDNoSeed, "This game has no seed.", http.StatusBadRequest},
+	{ErrSeedHidden, ErrorIDSeedHidden, "The seed of this game is not revealed yet.", http.StatusBadRequest},
+	{ErrAlreadyQueued, ErrorIDAlreadyQueued, "You are already waiting for an opponent.", http.StatusBadRequest},
+	{ErrNotQueued, ErrorIDNotQueued, "You are not waiting for an opponent.", http.StatusBadRequest},
+	{ErrNotTeamMember, ErrorIDNotTeamMember, "You are not a member of this team.", http.StatusForbidden},
+}
 
-	resp := FlipCardResponse{
-		Value: value,
+func (p *Plugin) handleJoinGame(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	p.handleGameAction(w, r, actingUserID, p.joinGame)
+}
+
+func (p *Plugin) handleLeaveGame(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	p.handleGameAction(w, r, actingUserID, p.leaveGame)
+}
+
+func (p *Plugin) handleBeginGame(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	p.handleGameAction(w, r, actingUserID, p.beginGame)
+}
+
+func (p *Plugin) handleResignGame(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	p.handleGameAction(w, r, actingUserID, p.resignGame)
+}
+
+func (p *Plugin) handleOfferDraw(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	p.handleGameAction(w, r, actingUserID, p.offerDraw)
+}
+
+func (p *Plugin) handleAcceptDraw(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	p.handleGameAction(w, r, actingUserID, p.acceptDraw)
+}
+
+// handleGameAction applies a change to the players of a game, and lets every
+// player know about the new state.
+func (p *Plugin) handleGameAction(w http.ResponseWriter, r *http.Request, actingUserID string, action func(gID, userID string) (*Game, error)) {
+	gameID, ok := mux.Vars(r)["gameID"]
+	if !ok {
+		p.mm.Log.Debug("No gameID")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
+		return
 	}
 
-	b, err := json.Marshal(resp)
+	game, err := action(gameID, actingUserID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		p.sendResyncWebsocket(actingUserID, game)
+		p.mm.Log.Debug("Cannot update game", "err", err)
+		p.writeGameError(w, err)
 		return
 	}
 
-	_, _ = w.Write(b)
+	p.sendResyncToPlayers(game, actingUserID)
 
-	p.mm.Frontend.PublishWebSocketEvent("flip", map[string]interface{}{"index": req.Index, "value": value, "gID": gameID}, &model.WebsocketBroadcast{UserId: otherPlayerID})
+	p.writeJSON(w, p.getGameResponse(game, actingUserID))
 }
 
-func (p *Plugin) sendResyncWebsocket(player string, game *Game) {
+// getScores returns the scores of the players of game in turn order.
+func getScores(game *Game) []int {
+	scores := []int{}
+	for _, player := range game.Players {
+		scores = append(scores, game.Scores[player])
+	}
+
+	return scores
+}
+
+// getGameResponse describes game from the point of view of userID.
+func (p *Plugin) getGameResponse(game *Game, userID string) *GetGameResponse {
 	values := []string{}
 	for i, flipped := range game.CardFlipped {
 		toAppend := CardBack
@@ -228,132 +372,820 @@ func (p *Plugin) sendResyncWebsocket(player string, game *Game) {
 		}
 		values = append(values, toAppend)
 	}
+	moves, _ := game.PlayerMoves()
 
-	opponentID := game.CurrentPlayer
-	if game.CurrentPlayer == player {
-		opponentID = game.OtherPlayer
+	return &GetGameResponse{
+		Values:        values,
+		Turn:          game.Started && game.CurrentPlayer() == userID,
+		LastFlipped:   game.LastFlipped,
+		MyScore:       game.Scores[userID],
+		Rows:          game.Rows,
+		Columns:       game.Columns,
+		Seq:           game.Seq,
+		Started:       game.Started,
+		IsOwner:       game.Owner == userID,
+		CurrentPlayer: p.getUsername(game.CurrentPlayer()),
+		Players:       p.getUsernames(game.Players),
+		Scores:        getScores(game),
+		DrawOffers:    p.getUsernames(game.DrawOffers),
+		Solo:          game.Solo,
+		Moves:         moves[userID],
+		CreateAt:      game.CreateAt,
+		SeedHash:      game.SeedHash,
 	}
+}
+
+func (p *Plugin) sendResyncWebsocket(player string, game *Game) {
+	resp := p.getGameResponse(game, player)
 
 	p.mm.Frontend.PublishWebSocketEvent("resync", map[string]interface{}{
-		"cards":         values,
-		"turn":          game.CurrentPlayer == player,
-		"lastFlipped":   game.LastFlipped,
+		"cards":         resp.Values,
+		"turn":          resp.Turn,
+		"lastFlipped":   resp.LastFlipped,
 		"gID":           game.GID,
-		"myScore":       game.Scores[player],
-		"opponentScore": game.Scores[opponentID],
+		"myScore":       resp.MyScore,
+		"seq":           resp.Seq,
+		"rows":          resp.Rows,
+		"columns":       resp.Columns,
+		"started":       resp.Started,
+		"isOwner":       resp.IsOwner,
+		"currentPlayer": resp.CurrentPlayer,
+		"players":       resp.Players,
+		"scores":        resp.Scores,
+		"drawOffers":    resp.DrawOffers,
+		"solo":          resp.Solo,
+		"moves":         resp.Moves,
 	}, &model.WebsocketBroadcast{UserId: player})
 }
 
+// sendResyncToPlayers sends the state of game to every player and to any
+// other user in extraUserIDs.
+func (p *Plugin) sendResyncToPlayers(game *Game, extraUserIDs ...string) {
+	for _, userID := range game.Players {
+		p.sendResyncWebsocket(userID, game)
+	}
+
+	for _, userID := range extraUserIDs {
+		if !game.IsPlayer(userID) {
+			p.sendResyncWebsocket(userID, game)
+		}
+	}
+}
+
 func (p *Plugin) handleStartGame(w http.ResponseWriter, r *http.Request, actingUserID string) {
 	req := StartGameRequest{}
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		p.mm.Log.Debug("Cannot decode", "err", err)
-		w.WriteHeader(http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidRequest, "Cannot decode the request body.")
+		return
+	}
+
+	if !model.IsValidId(req.ChannelID) {
+		p.mm.Log.Debug("Wrong channel id", "channelID", req.ChannelID)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid channel ID.")
+		return
+	}
+
+	if !p.mm.User.HasPermissionToChannel(actingUserID, req.ChannelID, model.PERMISSION_CREATE_POST) {
+		p.mm.Log.Debug("Cannot post in channel")
+		p.writeError(w, http.StatusForbidden, ErrorIDForbidden, "You cannot post in this channel.")
 		return
 	}
 
 	c, err := p.mm.Channel.Get(req.ChannelID)
 	if err != nil {
 		p.mm.Log.Debug("Cannot get channel", "err", err)
+		p.writeError(w, http.StatusNotFound, ErrorIDNotFound, "Channel not found.")
 		return
 	}
 
-	if c.Type != model.CHANNEL_DIRECT {
-		p.mm.Log.Debug(("Trying to start on non DM"))
-		w.WriteHeader(http.StatusBadRequest)
+	if _, ok := GetBoardSize(req.Difficulty); !ok {
+		p.mm.Log.Debug("Unknown difficulty", "difficulty", req.Difficulty)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Unknown difficulty.")
 		return
 	}
 
-	otherUser := c.GetOtherUserIdForDM(actingUserID)
-	game, err := p.NewGame(actingUserID, otherUser, c.Id)
-	if err != nil {
-		p.mm.Log.Debug("Cannot create", "err", err)
-		w.WriteHeader(http.StatusInternalServerError)
+	var resp StartGameResponse
+	if req.Solo {
+		var game *Game
+		game, err = p.startSoloGame(actingUserID, c, req.Difficulty)
+		if err != nil {
+			p.mm.Log.Debug("Cannot start solo game", "err", err)
+			p.writeGameError(w, err)
+			return
+		}
+		resp.GID = game.GID
+		resp.Turn = true
+	} else if c.Type == model.CHANNEL_DIRECT && c.GetOtherUserIdForDM(actingUserID) != p.BotUserID {
+		var inv *Invitation
+		inv, err = p.inviteToGame(actingUserID, c, req.Difficulty)
+		if err != nil {
+			p.mm.Log.Debug("Cannot invite to game", "err", err)
+			p.writeGameError(w, err)
+			return
+		}
+		resp.InvitationID = inv.ID
+	} else {
+		var game *Game
+		game, err = p.startGame(actingUserID, c, req.Difficulty)
+		if err != nil {
+			p.mm.Log.Debug("Cannot start game", "err", err)
+			p.writeGameError(w, err)
+			return
+		}
+		resp.GID = game.GID
+		resp.Turn = game.Started && game.CurrentPlayer() == actingUserID
+	}
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleRematchGame(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	gameID, ok := mux.Vars(r)["gameID"]
+	if !ok {
+		p.mm.Log.Debug("No game id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
 		return
 	}
 
-	err = p.setGame(game)
+	game, series, err := p.rematchGame(gameID, actingUserID)
 	if err != nil {
-		p.mm.Log.Debug("Cannot set game", "err", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		p.mm.Log.Debug("Cannot start rematch", "err", err)
+		p.writeGameError(w, err)
 		return
 	}
 
-	resp := StartGameResponse{
-		GID:  game.GID,
-		Turn: game.CurrentPlayer == actingUserID,
+	resp := RematchResponse{
+		GID:    game.GID,
+		Turn:   game.CurrentPlayer() == actingUserID,
+		Series: series,
 	}
 
-	b, err := json.Marshal(resp)
+	p.writeJSON(w, resp)
+}
+
+// handleRematchAction answers the post action of the rematch button of a
+// result post. Pressing it again returns the same rematch.
+func (p *Plugin) handleRematchAction(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	gameID, ok := mux.Vars(r)["gameID"]
+	if !ok {
+		p.mm.Log.Debug("No game id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
+		return
+	}
+
+	resp := &model.PostActionIntegrationResponse{}
+	game, series, err := p.rematchGame(gameID, actingUserID)
 	if err != nil {
-		p.mm.Log.Debug("cannot marshal", "err", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		p.mm.Log.Debug("Cannot start rematch", "err", err)
+		resp.EphemeralText = gameErrorMessage(err, "Cannot start the rematch.")
+	} else {
+		resp.EphemeralText = fmt.Sprintf("Rematch `%s` started. Series score: %s. Open the memory game to play.", game.GID, p.formatSeries(series))
+	}
+
+	_, _ = w.Write(resp.ToJson())
+}
+
+func (p *Plugin) handleAcceptInvitation(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	p.handleInvitationAction(w, r, actingUserID, func(id, userID string) (string, error) {
+		inv, _, err := p.acceptInvitation(id, userID)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("@%s accepted the invitation from @%s to play memory. Open the memory game to play.", p.getUsername(inv.Invitee), p.getUsername(inv.Inviter)), nil
+	})
+}
+
+func (p *Plugin) handleDeclineInvitation(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	p.handleInvitationAction(w, r, actingUserID, func(id, userID string) (string, error) {
+		inv, err := p.declineInvitation(id, userID)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("@%s declined the invitation from @%s to play memory.", p.getUsername(inv.Invitee), p.getUsername(inv.Inviter)), nil
+	})
+}
+
+// handleInvitationAction answers the post action of an invitation button.
+// On success, the invitation post is replaced by the message returned by
+// action, which drops its buttons.
+func (p *Plugin) handleInvitationAction(w http.ResponseWriter, r *http.Request, actingUserID string, action func(id, userID string) (string, error)) {
+	id, ok := mux.Vars(r)["invitationID"]
+	if !ok {
+		p.mm.Log.Debug("No invitation id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing invitation ID.")
 		return
 	}
 
-	u, err := p.mm.User.Get(actingUserID)
-	if err == nil {
-		_ = p.mm.Post.DM(p.BotUserID, otherUser, &model.Post{
-			Message: fmt.Sprintf("@%s started a memory game with you.", u.Username),
-		})
+	req := model.PostActionIntegrationRequestFromJson(r.Body)
+	if req == nil {
+		p.mm.Log.Debug("Cannot decode post action")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidRequest, "Cannot decode the post action.")
+		return
 	}
 
-	_, _ = w.Write(b)
+	resp := &model.PostActionIntegrationResponse{}
+	message, err := action(id, actingUserID)
+	switch {
+	case errors.Is(err, ErrInvitationNotFound):
+		resp.EphemeralText = "This invitation expired or was already answered."
+	case errors.Is(err, ErrNotInvited):
+		resp.EphemeralText = "This invitation is not for you."
+	case errors.Is(err, ErrTooManyGames):
+		resp.EphemeralText = fmt.Sprintf("Players cannot take part in more than %d unfinished games.", p.getConfiguration().MaxGamesPerUser)
+	case err != nil:
+		p.mm.Log.Debug("Cannot answer invitation", "err", err)
+		resp.EphemeralText = gameErrorMessage(err, "Cannot answer the invitation.")
+	default:
+		resp.Update = &model.Post{Message: message}
+	}
+
+	_, _ = w.Write(resp.ToJson())
 }
 
 func (p *Plugin) handleGetGame(w http.ResponseWriter, r *http.Request, actingUserID string) {
 	gameID, ok := mux.Vars(r)["gameID"]
 	if !ok {
 		p.mm.Log.Debug("No game id")
-		w.WriteHeader(http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
 		return
 	}
 
 	game, err := p.getGame(gameID)
 	if err != nil {
 		p.mm.Log.Debug("cannot get game", "err", err)
-		w.WriteHeader(http.StatusBadRequest)
+		p.writeGameError(w, err)
 		return
 	}
 
-	values := []string{}
-	for i, flipped := range game.CardFlipped {
-		toAppend := CardBack
-		if flipped {
-			toAppend = game.CardValues[i]
+	// Games in channels the user cannot read are hidden rather than
+	// forbidden.
+	if !p.canSeeGame(game, actingUserID) {
+		p.mm.Log.Debug("Cannot see game")
+		p.writeGameError(w, ErrGameNotFound)
+		return
+	}
+
+	resp := p.getGameResponse(game, actingUserID)
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleGetBoard(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	gameID, ok := mux.Vars(r)["gameID"]
+	if !ok {
+		p.mm.Log.Debug("No game id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
+		return
+	}
+
+	game, err := p.getGame(gameID)
+	if err != nil {
+		p.mm.Log.Debug("cannot get game", "err", err)
+		p.writeGameError(w, err)
+		return
+	}
+
+	if !p.canSeeGame(game, actingUserID) {
+		p.mm.Log.Debug("Cannot see game")
+		p.writeError(w, http.StatusForbidden, ErrorIDForbidden, "You cannot see this game.")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = BoardFormatMarkdown
+	}
+
+	board, ok := p.renderBoard(game, format)
+	if !ok {
+		p.mm.Log.Debug("Unknown board format", "format", format)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Unknown board format.")
+		return
+	}
+
+	p.writeJSON(w, BoardResponse{GID: game.GID, Format: format, Board: board})
+}
+
+func (p *Plugin) handleGetSnapshot(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	gameID, ok := mux.Vars(r)["gameID"]
+	if !ok {
+		p.mm.Log.Debug("No game id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
+		return
+	}
+
+	game, err := p.getGame(gameID)
+	if errors.Is(err, ErrGameNotFound) {
+		game, err = p.getArchivedGame(gameID)
+	}
+	if err != nil {
+		p.mm.Log.Debug("cannot get game", "err", err)
+		p.writeGameError(w, err)
+		return
+	}
+
+	if !p.canSeeGame(game, actingUserID) {
+		p.mm.Log.Debug("Cannot see game")
+		p.writeError(w, http.StatusForbidden, ErrorIDForbidden, "You cannot see this game.")
+		return
+	}
+
+	snapshot, err := p.renderSnapshot(game)
+	if err != nil {
+		p.mm.Log.Warn("Cannot render snapshot", "gameID", game.GID, "err", err.Error())
+		p.writeInternalError(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(snapshot)
+}
+
+func (p *Plugin) handleGetChannelGames(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	channelID, ok := mux.Vars(r)["channelID"]
+	if !ok {
+		p.mm.Log.Debug("No channel id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing channel ID.")
+		return
+	}
+
+	if !p.mm.User.HasPermissionToChannel(actingUserID, channelID, model.PERMISSION_READ_CHANNEL) {
+		p.mm.Log.Debug("Cannot read channel")
+		p.writeError(w, http.StatusForbidden, ErrorIDForbidden, "You cannot read this channel.")
+		return
+	}
+
+	games, err := p.getChannelGames(channelID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get channel games", "err", err)
+		p.writeInternalError(w)
+		return
+	}
+
+	resp := []*GameSummary{}
+	for _, game := range games {
+		resp = append(resp, p.getGameSummary(game, actingUserID))
+	}
+
+	p.writeJSON(w, resp)
+}
+
+// getGameSummary describes game from the point of view of userID.
+func (p *Plugin) getGameSummary(game *Game, userID string) *GameSummary {
+	return &GameSummary{
+		GID:           game.GID,
+		Started:       game.Started,
+		IsPlayer:      game.IsPlayer(userID),
+		CurrentPlayer: p.getUsername(game.CurrentPlayer()),
+		Players:       p.getUsernames(game.Players),
+		Rows:          game.Rows,
+		Columns:       game.Columns,
+		Solo:          game.Solo,
+		Daily:         game.Daily,
+	}
+}
+
+// getPagination reads the page and per_page query parameters.
+func getPagination(r *http.Request) (page, perPage int, ok bool) {
+	page, perPage = 0, defaultPerPage
+
+	query := r.URL.Query()
+	if value := query.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, false
 		}
-		values = append(values, toAppend)
+		page = parsed
 	}
 
-	opponentID := game.CurrentPlayer
-	if game.CurrentPlayer == actingUserID {
-		opponentID = game.OtherPlayer
+	if value := query.Get("per_page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return 0, 0, false
+		}
+		perPage = parsed
 	}
 
-	opponentName := "unknonw"
-	u, err := p.mm.User.Get(opponentID)
-	if err == nil {
-		opponentName = u.Username
+	if perPage > maxPerPage {
+		perPage = maxPerPage
 	}
 
-	resp := GetGameResponse{
-		Values:        values,
-		Turn:          game.CurrentPlayer == actingUserID,
-		LastFlipped:   game.LastFlipped,
-		OpponentName:  opponentName,
-		MyScore:       game.Scores[actingUserID],
-		OpponentScore: game.Scores[opponentID],
+	return page, perPage, true
+}
+
+func (p *Plugin) handleGetStats(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	userID, ok := mux.Vars(r)["userID"]
+	if !ok || !model.IsValidId(userID) {
+		p.mm.Log.Debug("Wrong user id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid user ID.")
+		return
 	}
 
-	b, err := json.Marshal(resp)
+	stats, err := p.getPlayerStats(userID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		p.mm.Log.Debug("Cannot get stats", "err", err)
+		p.writeInternalError(w)
 		return
 	}
 
-	_, _ = w.Write(b)
+	botStats, err := p.getBotStats(userID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get stats against the bot", "err", err)
+		p.writeInternalError(w)
+		return
+	}
+
+	opponents := []OpponentRecord{}
+	for opponent, record := range stats.Opponents {
+		opponents = append(opponents, OpponentRecord{
+			UserID:      opponent,
+			Username:    p.getUsername(opponent),
+			Wins:        record.Wins,
+			Losses:      record.Losses,
+			Draws:       record.Draws,
+			GamesPlayed: record.GamesPlayed,
+		})
+	}
+	sort.Slice(opponents, func(i, j int) bool {
+		if opponents[i].GamesPlayed != opponents[j].GamesPlayed {
+			return opponents[i].GamesPlayed > opponents[j].GamesPlayed
+		}
+		return opponents[i].UserID < opponents[j].UserID
+	})
+
+	resp := PlayerStatsResponse{
+		UserID:             userID,
+		Username:           p.getUsername(userID),
+		Wins:               stats.Wins,
+		Losses:             stats.Losses,
+		Draws:              stats.Draws,
+		GamesPlayed:        stats.GamesPlayed,
+		WinRate:            stats.WinRate(),
+		BestWinStreak:      stats.BestWinStreak,
+		PairsMatched:       stats.PairsMatched,
+		LongestMatchStreak: stats.LongestMatchStreak,
+		AverageMoves:       stats.AverageMoves(),
+		Opponents:          opponents,
+		BotGames:           botStats.GameRecord,
+	}
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleGetRating(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	userID, ok := mux.Vars(r)["userID"]
+	if !ok || !model.IsValidId(userID) {
+		p.mm.Log.Debug("Wrong user id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid user ID.")
+		return
+	}
+
+	rating, err := p.getRating(userID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get rating", "err", err)
+		p.writeInternalError(w)
+		return
+	}
+
+	history := rating.History
+	if history == nil {
+		history = []RatingChange{}
+	}
+
+	p.writeJSON(w, RatingResponse{
+		UserID:      userID,
+		Username:    p.getUsername(userID),
+		Rating:      rating.Rating,
+		Provisional: rating.Provisional(),
+		Games:       rating.Games,
+		Peak:        rating.Peak,
+		History:     history,
+	})
+}
+
+func (p *Plugin) handleGetLeaderboard(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	page, perPage, ok := getPagination(r)
+	if !ok {
+		p.mm.Log.Debug("Wrong pagination")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid page or per_page.")
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if _, ok = leaderboardKey(period, time.Now()); !ok {
+		p.mm.Log.Debug("Wrong period", "period", period)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Unknown period.")
+		return
+	}
+
+	teamID := r.URL.Query().Get("team_id")
+	if teamID != "" && !p.mm.User.HasPermissionToTeam(actingUserID, teamID, model.PERMISSION_VIEW_TEAM) {
+		p.mm.Log.Debug("Cannot see team")
+		p.writeError(w, http.StatusForbidden, ErrorIDForbidden, "You cannot see this team.")
+		return
+	}
+
+	resp, err := p.getRankedLeaderboard(period, teamID, page, perPage)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get leaderboard", "err", err)
+		p.writeInternalError(w)
+		return
+	}
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleGetSoloStats(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	userID, ok := mux.Vars(r)["userID"]
+	if !ok || !model.IsValidId(userID) {
+		p.mm.Log.Debug("Wrong user id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid user ID.")
+		return
+	}
+
+	bests, err := p.getSoloBests(userID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get solo bests", "err", err)
+		p.writeInternalError(w)
+		return
+	}
+
+	resp := SoloStatsResponse{
+		UserID:   userID,
+		Username: p.getUsername(userID),
+		Bests:    bests,
+	}
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleGetSoloLeaderboard(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	page, perPage, ok := getPagination(r)
+	if !ok {
+		p.mm.Log.Debug("Wrong pagination")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid page or per_page.")
+		return
+	}
+
+	difficulty := r.URL.Query().Get("difficulty")
+	if difficulty == "" {
+		difficulty = DifficultyEasy
+	}
+	if _, ok = GetBoardSize(difficulty); !ok {
+		p.mm.Log.Debug("Unknown difficulty", "difficulty", difficulty)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Unknown difficulty.")
+		return
+	}
+
+	resp, err := p.getRankedSoloLeaderboard(difficulty, page, perPage)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get solo leaderboard", "err", err)
+		p.writeInternalError(w)
+		return
+	}
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleStartDailyChallenge(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	game, err := p.startDailyChallenge(actingUserID, time.Now())
+	if err != nil {
+		p.mm.Log.Debug("Cannot start daily challenge", "err", err)
+		p.writeGameError(w, err)
+		return
+	}
+
+	resp := StartGameResponse{
+		GID:  game.GID,
+		Turn: true,
+	}
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleGetDailyChallenge(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	page, perPage, ok := getPagination(r)
+	if !ok {
+		p.mm.Log.Debug("Wrong pagination")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid page or per_page.")
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = GetDailyDate(time.Now())
+	}
+	if _, err := time.Parse(dailyDateLayout, date); err != nil {
+		p.mm.Log.Debug("Wrong date", "date", date)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid date. Use the YYYY-MM-DD format.")
+		return
+	}
+
+	resp, err := p.getDailyChallengeResponse(date, actingUserID, page, perPage)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get daily challenge", "err", err)
+		p.writeInternalError(w)
+		return
+	}
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleJoinMatchmaking(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	req := MatchmakingRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		p.mm.Log.Debug("Cannot decode", "err", err)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidRequest, "Cannot decode the request body.")
+		return
+	}
+
+	if req.TeamID != "" && !model.IsValidId(req.TeamID) {
+		p.mm.Log.Debug("Wrong team id", "teamID", req.TeamID)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid team ID.")
+		return
+	}
+
+	if req.RatingWindow < 0 {
+		p.mm.Log.Debug("Negative rating window", "ratingWindow", req.RatingWindow)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "The rating window cannot be negative.")
+		return
+	}
+
+	if _, ok := GetBoardSize(req.Difficulty); req.Difficulty != "" && !ok {
+		p.mm.Log.Debug("Unknown difficulty", "difficulty", req.Difficulty)
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Unknown difficulty.")
+		return
+	}
+
+	game, err := p.joinMatchmaking(actingUserID, &req)
+	if err != nil {
+		p.mm.Log.Debug("Cannot join matchmaking", "err", err)
+		p.writeGameError(w, err)
+		return
+	}
+
+	resp := MatchmakingResponse{Queued: game == nil}
+	if game != nil {
+		resp.GID = game.GID
+		resp.ChannelID = game.ChannelID
+	}
+
+	p.writeJSON(w, resp)
+}
+
+func (p *Plugin) handleLeaveMatchmaking(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	err := p.leaveMatchmaking(actingUserID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot leave matchmaking", "err", err)
+		p.writeGameError(w, err)
+		return
+	}
+
+	p.writeJSON(w, MatchmakingResponse{})
+}
+
+func (p *Plugin) handleGetHistory(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	page, perPage, ok := getPagination(r)
+	if !ok {
+		p.mm.Log.Debug("Wrong pagination")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Invalid page or per_page.")
+		return
+	}
+
+	games, err := p.getHistory(actingUserID, page, perPage)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get history", "err", err)
+		p.writeInternalError(w)
+		return
+	}
+
+	resp := []*HistoryEntry{}
+	for _, game := range games {
+		resp = append(resp, p.getHistoryEntry(game))
+	}
+
+	p.writeJSON(w, resp)
+}
+
+// getHistoryEntry describes a finished game.
+func (p *Plugin) getHistoryEntry(game *Game) *HistoryEntry {
+	participants := game.Participants()
+	scores := []int{}
+	for _, player := range participants {
+		scores = append(scores, game.Scores[player])
+	}
+
+	winner := ""
+	if winnerID := game.Winner(); winnerID != "" {
+		winner = p.getUsername(winnerID)
+	}
+
+	entry := &HistoryEntry{
+		GID:       game.GID,
+		ChannelID: game.ChannelID,
+		Players:   p.getUsernames(participants),
+		Scores:    scores,
+		Winner:    winner,
+		Rows:      game.Rows,
+		Columns:   game.Columns,
+		CreateAt:  game.CreateAt,
+		EndAt:     game.EndAt,
+		SeedHash:  game.SeedHash,
+	}
+	if game.SeedHash != "" && game.SeedRevealed(time.Now()) {
+		entry.Seed = strconv.FormatInt(game.Seed, 10)
+	}
+
+	return entry
+}
+
+func (p *Plugin) handleGetReplay(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	gameID, ok := mux.Vars(r)["gameID"]
+	if !ok {
+		p.mm.Log.Debug("No game id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
+		return
+	}
+
+	game, err := p.getArchivedGame(gameID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get archived game", "err", err)
+		p.writeGameError(w, err)
+		return
+	}
+
+	if !p.canSeeGame(game, actingUserID) {
+		p.mm.Log.Debug("Cannot see game")
+		p.writeError(w, http.StatusForbidden, ErrorIDForbidden, "You cannot see this game.")
+		return
+	}
+
+	usernames := map[string]string{}
+	for _, player := range game.Participants() {
+		usernames[player] = p.getUsername(player)
+	}
+
+	moves := []ReplayMove{}
+	for _, event := range game.Events {
+		moves = append(moves, ReplayMove{
+			Type:   event.Type,
+			Player: usernames[event.UserID],
+			Index:  event.Index,
+			Value:  event.Value,
+			At:     event.At,
+		})
+	}
+
+	resp := ReplayResponse{
+		HistoryEntry: *p.getHistoryEntry(game),
+		Cards:        game.CardValues,
+		Moves:        moves,
+	}
+
+	p.writeJSON(w, resp)
+}
+
+// canSeeGame returns whether userID took part in game or can read its
+// channel.
+func (p *Plugin) canSeeGame(game *Game, userID string) bool {
+	return containsString(game.Participants(), userID) || p.mm.User.HasPermissionToChannel(userID, game.ChannelID, model.PERMISSION_READ_CHANNEL)
+}
+
+func (p *Plugin) handleVerifyGame(w http.ResponseWriter, r *http.Request, actingUserID string) {
+	gameID, ok := mux.Vars(r)["gameID"]
+	if !ok {
+		p.mm.Log.Debug("No game id")
+		p.writeError(w, http.StatusBadRequest, ErrorIDInvalidParameter, "Missing game ID.")
+		return
+	}
+
+	game, err := p.getArchivedGame(gameID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get archived game", "err", err)
+		p.writeGameError(w, err)
+		return
+	}
+
+	if !p.canSeeGame(game, actingUserID) {
+		p.mm.Log.Debug("Cannot see game")
+		p.writeError(w, http.StatusForbidden, ErrorIDForbidden, "You cannot see this game.")
+		return
+	}
+
+	resp, err := verifyGameSeed(game, time.Now())
+	if err != nil {
+		p.mm.Log.Debug("Cannot verify game", "err", err)
+		p.writeGameError(w, err)
+		return
+	}
+
+	p.writeJSON(w, resp)
 }
 
 func (p *Plugin) extractUserMiddleWare(handler HTTPHandlerFuncWithUser, responseType ResponseType) http.HandlerFunc {
@@ -362,7 +1194,7 @@ func (p *Plugin) extractUserMiddleWare(handler HTTPHandlerFuncWithUser, response
 		if userID == "" {
 			switch responseType {
 			case ResponseTypeJSON:
-				p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Not authorized.", StatusCode: http.StatusUnauthorized})
+				p.writeError(w, http.StatusUnauthorized, ErrorIDNotAuthorized, "Not authorized.")
 			case ResponseTypePlain:
 				http.Error(w, "Not authorized", http.StatusUnauthorized)
 			case ResponseTypeDialog:
@@ -377,18 +1209,39 @@ func (p *Plugin) extractUserMiddleWare(handler HTTPHandlerFuncWithUser, response
 	}
 }
 
+// recoveryResponseWriter records whether a response was started, so that
+// withRecovery only writes an error when nothing was sent yet.
+type recoveryResponseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *recoveryResponseWriter) WriteHeader(statusCode int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recoveryResponseWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
 func (p *Plugin) withRecovery(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryResponseWriter{ResponseWriter: w}
 		defer func() {
 			if x := recover(); x != nil {
 				p.mm.Log.Error("Recovered from a panic",
 					"url", r.URL.String(),
 					"error", x,
 					"stack", string(debug.Stack()))
+				if !rw.started {
+					p.writeInternalError(rw)
+				}
 			}
 		}()
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(rw, r)
 	})
 }
 
@@ -400,12 +1253,65 @@ func (p *Plugin) writeAPIError(w http.ResponseWriter, apiErr *APIErrorResponse)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(apiErr.StatusCode)
 
 	_, err = w.Write(b)
 	if err != nil {
 		p.mm.Log.Warn("Failed to write JSON response", "error", err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 }
+
+// writeError writes an APIErrorResponse with the given status code, error ID
+// and message.
+func (p *Plugin) writeError(w http.ResponseWriter, statusCode int, id, message string) {
+	p.writeAPIError(w, &APIErrorResponse{ID: id, Message: message, StatusCode: statusCode})
+}
+
+// writeInternalError writes an APIErrorResponse for an unexpected error,
+// without leaking its details.
+func (p *Plugin) writeInternalError(w http.ResponseWriter) {
+	p.writeError(w, http.StatusInternalServerError, ErrorIDInternal, "An internal error occurred.")
+}
+
+// gameErrorMessage returns the message shown to users for an error returned
+// while acting on a game, or fallback if the error is unexpected.
+func gameErrorMessage(err error, fallback string) string {
+	for _, gameErr := range gameErrors {
+		if errors.Is(err, gameErr.err) {
+			return gameErr.message
+		}
+	}
+
+	return fallback
+}
+
+// writeGameError writes the APIErrorResponse matching an error returned while
+// acting on a game.
+func (p *Plugin) writeGameError(w http.ResponseWriter, err error) {
+	for _, gameErr := range gameErrors {
+		if errors.Is(err, gameErr.err) {
+			p.writeAPIError(w, &APIErrorResponse{
+				ID:         gameErr.id,
+				Message:    gameErr.message,
+				StatusCode: gameErr.statusCode,
+			})
+			return
+		}
+	}
+
+	p.writeInternalError(w)
+}
+
+// writeJSON writes v as the JSON body of the response.
+func (p *Plugin) writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		p.mm.Log.Warn("Failed to marshal response", "error", err.Error())
+		p.writeInternalError(w)
+		return
+	}
+
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeAPIError(t *testing.T, w *httptest.ResponseRecorder) *APIErrorResponse {
+	t.Helper()
+
+	apiErr := &APIErrorResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(apiErr))
+	assert.Equal(t, w.Code, apiErr.StatusCode)
+
+	return apiErr
+}
+
+func TestAPIErrors(t *testing.T) {
+	p := newTestPlugin(t)
+	newTestGame(t, p)
+
+	for name, tc := range map[string]struct {
+		userID     string
+		gameID     string
+		body       string
+		statusCode int
+		id         string
+	}{
+		"bad body":        {"user1", "game", "{", http.StatusBadRequest, ErrorIDInvalidRequest},
+		"negative index":  {"user1", "game", `{"index": -1}`, http.StatusBadRequest, ErrorIDIndexOutOfRange},
+		"index too large": {"user1", "game", `{"index": 4}`, http.StatusBadRequest, ErrorIDIndexOutOfRange},
+		"unknown game":    {"user1", "other", `{"index": 0}`, http.StatusNotFound, ErrorIDGameNotFound},
+		"wrong player":    {"user2", "game", `{"index": 0}`, http.StatusBadRequest, ErrorIDNotYourTurn},
+		"stale move":      {"user1", "game", `{"index": 0, "seq": 3}`, http.StatusConflict, ErrorIDStaleMove},
+		"no user":         {"", "game", `{"index": 0}`, http.StatusUnauthorized, ErrorIDNotAuthorized},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/game/"+tc.gameID+"/flip", strings.NewReader(tc.body))
+			r.Header.Set("Mattermost-User-ID", tc.userID)
+
+			p.router.ServeHTTP(w, r)
+
+			require.Equal(t, tc.statusCode, w.Code)
+			assert.Equal(t, tc.id, decodeAPIError(t, w).ID)
+		})
+	}
+}
+
+func TestGetGameAccess(t *testing.T) {
+	p := newTestPlugin(t)
+	game := newTestGame(t, p)
+	game.ChannelID = "private"
+	require.NoError(t, p.setGame(game))
+	p.API.(*fakeAPI).readers["private"] = []string{"user3"}
+
+	for userID, statusCode := range map[string]int{
+		"user1": http.StatusOK,
+		"user3": http.StatusOK,
+		"user4": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/game/game", nil)
+		r.Header.Set("Mattermost-User-ID", userID)
+
+		p.router.ServeHTTP(w, r)
+
+		require.Equal(t, statusCode, w.Code, userID)
+		if statusCode == http.StatusNotFound {
+			assert.Equal(t, ErrorIDGameNotFound, decodeAPIError(t, w).ID)
+		}
+	}
+}
+
+func TestWithRecovery(t *testing.T) {
+	p := newTestPlugin(t)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	p.withRecovery(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})).ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, ErrorIDInternal, decodeAPIError(t, w).ID)
+}
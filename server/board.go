@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// cardSuits maps the suits of the card values to their symbols.
+var cardSuits = []struct { //nolint: gochecknoglobals
+	name   string
+	symbol string
+}{
+	{"hearts", "♥"},
+	{"diamonds", "♦"},
+	{"clubs", "♣"},
+	{"spades", "♠"},
+}
+
+// CardLabel returns a short label of a card value, like A♥ for heartsAce.
+func CardLabel(value string) string {
+	if value == "joker" {
+		return "Joker"
+	}
+
+	for _, suit := range cardSuits {
+		if !strings.HasPrefix(value, suit.name) {
+			continue
+		}
+
+		rank := strings.TrimPrefix(value, suit.name)
+		if _, err := strconv.Atoi(rank); err != nil && rank != "" {
+			rank = rank[:1]
+		}
+		return rank + suit.symbol
+	}
+
+	return value
+}
+
+// createBoardPost posts the board of game for clients without the webapp
+// plugin and keeps the ID of the post in the game, which is not stored.
+func (p *Plugin) createBoardPost(game *Game) {
+	post := p.boardPost(game)
+	err := p.mm.Post.CreatePost(post)
+	if err != nil {
+		p.mm.Log.Warn("Cannot post board", "gameID", game.GID, "err", err.Error())
+		return
+	}
+
+	game.BoardPostID = post.Id
+}
+
+// updateBoardPost refreshes the board post of game, if it has one.
+func (p *Plugin) updateBoardPost(game *Game) {
+	if game.BoardPostID == "" {
+		return
+	}
+
+	post := p.boardPost(game)
+	post.Id = game.BoardPostID
+	err := p.mm.Post.UpdatePost(post)
+	if err != nil {
+		p.mm.Log.Warn("Cannot update board post", "gameID", game.GID, "err", err.Error())
+	}
+}
+
+// boardPost returns the bot post showing game. While the game is played, it
+// has a row of buttons per row of cards. Face down cards show their number
+// and face up cards their label.
+func (p *Plugin) boardPost(game *Game) *model.Post {
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: game.ChannelID,
+		Message:   p.describeBoard(game),
+	}
+
+	if !game.Started || game.Over {
+		return post
+	}
+
+	attachments := []*model.SlackAttachment{}
+	for row := 0; row < game.Rows; row++ {
+		actions := []*model.PostAction{}
+		for column := 0; column < game.Columns; column++ {
+			actions = append(actions, boardAction(game, row*game.Columns+column))
+		}
+		attachments = append(attachments, &model.SlackAttachment{Actions: actions})
+	}
+	model.ParseSlackAttachment(post, attachments)
+
+	return post
+}
+
+func boardAction(game *Game, index int) *model.PostAction {
+	name := strconv.Itoa(index + 1)
+	if game.CardFlipped[index] {
+		name = CardLabel(game.CardValues[index])
+	}
+
+	return &model.PostAction{
+		Id:   "card" + strconv.Itoa(index),
+		Type: model.POST_ACTION_TYPE_BUTTON,
+		Name: name,
+		Integration: &model.PostActionIntegration{
+			URL: fmt.Sprintf("/plugins/%s/api/v1/game/%s/board/flip", manifest.Id, game.GID),
+			Context: map[string]interface{}{
+				"index": index,
+				"seq":   game.Seq,
+			},
+		},
+	}
+}
+
+// describeBoard returns the Markdown message of the board post of game.
+func (p *Plugin) describeBoard(game *Game) string {
+	text := "#### Memory game\n" + p.describeStatus(game) + "\n" + p.describeScores(game)
+
+	if lastTurn := p.describeMismatch(game); lastTurn != "" {
+		text += "\n" + lastTurn + "\n"
+	}
+
+	return text
+}
+
+// describeStatus tells who has to move in game, or how it ended.
+func (p *Plugin) describeStatus(game *Game) string {
+	switch {
+	case !game.Started:
+		return fmt.Sprintf("Waiting for @%s to begin the game.", p.getUsername(game.Owner))
+	case game.Over && game.Abandoned:
+		return "The game was removed after being abandoned."
+	case game.Over && game.Drawn:
+		return "The game ended in a draw."
+	case game.Over && game.Winner() != "":
+		return fmt.Sprintf("@%s won the game.", p.getUsername(game.Winner()))
+	case game.Over:
+		return "The game is over."
+	default:
+		return fmt.Sprintf("It is @%s's turn.", p.getUsername(game.CurrentPlayer()))
+	}
+}
+
+// describeScores lists the scores of the players of game, including the ones
+// that resigned, as a Markdown list.
+func (p *Plugin) describeScores(game *Game) string {
+	text := ""
+	for _, player := range game.Players {
+		text += fmt.Sprintf("* @%s: %d pairs\n", p.getUsername(player), game.Scores[player])
+	}
+	for _, player := range game.Resignations {
+		text += fmt.Sprintf("* @%s: %d pairs, resigned\n", p.getUsername(player), game.Scores[player])
+	}
+
+	return text
+}
+
+// describeMismatch tells which cards were turned over in the last move of
+// game if they did not match, since the board shows them face down again.
+func (p *Plugin) describeMismatch(game *Game) string {
+	if game.LastFlipped != -1 || len(game.Events) < 2 {
+		return ""
+	}
+
+	first, second := game.Events[len(game.Events)-2], game.Events[len(game.Events)-1]
+	if first.Type != EventTypeFlip || second.Type != EventTypeFlip || first.UserID != second.UserID || first.Value == second.Value {
+		return ""
+	}
+
+	return fmt.Sprintf("@%s turned over %s (card %d) and %s (card %d), which do not match.",
+		p.getUsername(second.UserID), CardLabel(first.Value), first.Index+1, CardLabel(second.Value), second.Index+1)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pressCard(p *Plugin, userID, gameID string, index, seq int) *model.PostActionIntegrationResponse {
+	req := &model.PostActionIntegrationRequest{
+		UserId:  userID,
+		Context: map[string]interface{}{"index": index, "seq": seq},
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/game/"+gameID+"/board/flip", bytes.NewReader(req.ToJson()))
+	r.Header.Set("Mattermost-User-ID", userID)
+
+	p.router.ServeHTTP(w, r)
+
+	return model.PostActionIntegrationResponseFromJson(w.Result().Body)
+}
+
+func TestCardLabel(t *testing.T) {
+	assert.Equal(t, "A♥", CardLabel("heartsAce"))
+	assert.Equal(t, "Q♠", CardLabel("spadesQueen"))
+	assert.Equal(t, "10♥", CardLabel("hearts10"))
+	assert.Equal(t, "Joker", CardLabel("joker"))
+}
+
+func TestBoardPost(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableDirectMessages: true, EnableBoardPosts: true})
+	api := p.API.(*fakeAPI)
+
+	c, err := p.mm.Channel.Get("user1__user2")
+	require.NoError(t, err)
+	game, err := p.startGame("user1", c, DifficultyEasy)
+	require.NoError(t, err)
+	require.NotEmpty(t, game.BoardPostID)
+	require.Len(t, api.posts, 1)
+	require.Len(t, api.posts[0].Attachments(), game.Rows)
+	assert.Len(t, api.posts[0].Attachments()[0].Actions, game.Columns)
+
+	waiting := game.Players[1]
+	resp := pressCard(p, waiting, game.GID, 0, game.Seq)
+	assert.Equal(t, "It is not your turn.", resp.EphemeralText)
+
+	second := -1
+	for i, value := range game.CardValues {
+		if value != game.CardValues[0] {
+			second = i
+			break
+		}
+	}
+	require.NotEqual(t, -1, second)
+
+	current := game.CurrentPlayer()
+	resp = pressCard(p, current, game.GID, 0, game.Seq)
+	assert.Empty(t, resp.EphemeralText)
+	require.Len(t, api.posts, 2)
+	assert.Equal(t, game.BoardPostID, api.posts[1].Id)
+	assert.Equal(t, CardLabel(game.CardValues[0]), api.posts[1].Attachments()[0].Actions[0].Name)
+
+	resp = pressCard(p, current, game.GID, second, game.Seq)
+	assert.Equal(t, "The game changed since your last move.", resp.EphemeralText)
+
+	resp = pressCard(p, current, game.GID, second, game.Seq+1)
+	assert.Empty(t, resp.EphemeralText)
+	require.Len(t, api.posts, 3)
+	assert.Contains(t, api.posts[2].Message, "which do not match")
+	assert.Equal(t, "1", api.posts[2].Attachments()[0].Actions[0].Name)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// botThinkingTime is the shortest time the bot waits before flipping a
+	// card, so that players can follow its moves.
+	botThinkingTime = time.Second
+	// botThinkingJitter is the longest extra time the bot waits before
+	// flipping a card.
+	botThinkingJitter = time.Second
+)
+
+// BotSkill is how well the bot remembers the cards turned over in its games.
+type BotSkill struct {
+	// Accuracy is the probability the bot remembers a card turned over by
+	// another player. The bot always remembers its own cards.
+	Accuracy float64
+	// Forgetting is the probability the bot forgets each card it remembers
+	// whenever another card is turned over.
+	Forgetting float64
+}
+
+// GetBotSkill returns the skill of the bot at the given memory level.
+func GetBotSkill(memory string) (BotSkill, bool) {
+	switch memory {
+	case BotMemoryEasy:
+		return BotSkill{Accuracy: 0.3, Forgetting: 0.2}, true
+	case BotMemoryMedium, "":
+		return BotSkill{Accuracy: 0.7, Forgetting: 0.05}, true
+	case BotMemoryPerfect:
+		return BotSkill{Accuracy: 1}, true
+	default:
+		return BotSkill{}, false
+	}
+}
+
+// rememberCards updates the cards the bot remembers in game with the cards
+// turned over since it last looked at the game. The memory is kept in the
+// game, so that it builds up and fades across turns.
+func (g *Game) rememberCards(botUserID string, skill BotSkill, rng *rand.Rand) {
+	if g.BotSeenEvents > len(g.Events) {
+		g.BotSeenEvents = 0
+	}
+
+	for _, event := range g.Events[g.BotSeenEvents:] {
+		if event.Type != EventTypeFlip {
+			continue
+		}
+
+		known := []int{}
+		for _, index := range g.BotKnownCards {
+			if index != event.Index && rng.Float64() < skill.Forgetting {
+				continue
+			}
+			known = append(known, index)
+		}
+		if !containsInt(known, event.Index) && (event.UserID == botUserID || rng.Float64() < skill.Accuracy) {
+			known = append(known, event.Index)
+		}
+		g.BotKnownCards = known
+	}
+	g.BotSeenEvents = len(g.Events)
+
+	// Matched cards do not need to be remembered anymore.
+	known := []int{}
+	for _, index := range g.BotKnownCards {
+		if !g.CardFlipped[index] || index == g.LastFlipped {
+			known = append(known, index)
+		}
+	}
+	g.BotKnownCards = known
+}
+
+// chooseBotCard returns the card the bot flips next in game. The bot
+// completes the pairs it remembers and otherwise flips a card it does not
+// know.
+func chooseBotCard(game *Game, rng *rand.Rand) int {
+	known := []int{}
+	for _, index := range game.BotKnownCards {
+		if !game.CardFlipped[index] {
+			known = append(known, index)
+		}
+	}
+	sort.Ints(known)
+
+	isKnown := map[int]bool{}
+	for _, index := range known {
+		isKnown[index] = true
+	}
+	unknown := []int{}
+	for index, flipped := range game.CardFlipped {
+		if !flipped && !isKnown[index] {
+			unknown = append(unknown, index)
+		}
+	}
+
+	if game.LastFlipped != -1 {
+		value := game.CardValues[game.LastFlipped]
+		for _, index := range known {
+			if game.CardValues[index] == value {
+				return index
+			}
+		}
+	} else {
+		byValue := map[string]int{}
+		for _, index := range known {
+			if other, ok := byValue[game.CardValues[index]]; ok {
+				return other
+			}
+			byValue[game.CardValues[index]] = index
+		}
+	}
+
+	if len(unknown) == 0 {
+		return known[rng.Intn(len(known))]
+	}
+
+	return unknown[rng.Intn(len(unknown))]
+}
+
+// startBotTurn makes the bot play in the background if it has to move in
+// game. A single background turn runs per game.
+func (p *Plugin) startBotTurn(game *Game) {
+	if !game.Started || game.Over || game.CurrentPlayer() != p.BotUserID {
+		return
+	}
+
+	if _, playing := p.botTurns.LoadOrStore(game.GID, true); playing {
+		return
+	}
+
+	go func() {
+		defer p.botTurns.Delete(game.GID)
+		p.playBotTurn(game.GID)
+	}()
+}
+
+// playBotTurn flips cards on behalf of the bot until it is not its turn
+// anymore.
+func (p *Plugin) playBotTurn(gID string) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for {
+		time.Sleep(botThinkingTime + time.Duration(rng.Int63n(int64(botThinkingJitter))))
+
+		game, err := p.getGame(gID)
+		if err != nil {
+			if !errors.Is(err, ErrGameNotFound) {
+				p.mm.Log.Warn("Cannot get game", "gameID", gID, "err", err.Error())
+			}
+			return
+		}
+		if !game.Started || game.Over || game.CurrentPlayer() != p.BotUserID {
+			return
+		}
+
+		skill, _ := GetBotSkill(game.BotMemory)
+		game.rememberCards(p.BotUserID, skill, rng)
+		index := chooseBotCard(game, rng)
+
+		var value string
+		game, err = p.updateGame(gID, func(stored *Game) error {
+			if stored.Seq != game.Seq {
+				return ErrStaleMove
+			}
+			stored.BotKnownCards = game.BotKnownCards
+			stored.BotSeenEvents = game.BotSeenEvents
+
+			var flipErr error
+			value, flipErr = stored.Flip(p.BotUserID, index)
+			return flipErr
+		})
+		if errors.Is(err, ErrStaleMove) {
+			continue
+		}
+		if err != nil {
+			p.mm.Log.Warn("Bot cannot flip card", "gameID", gID, "err", err.Error())
+			return
+		}
+
+		if game.Over {
+			p.finishGame(game)
+		}
+
+		p.sendFlipWebsocket(game, p.BotUserID, index, value)
+
+		if game.Over || game.CurrentPlayer() != p.BotUserID {
+			return
+		}
+	}
+}
+
+func botStatsKey(userID string) string {
+	return KeyPrefixBotStats + userID
+}
+
+// getBotStats returns the stats of userID in games against the bot.
+func (p *Plugin) getBotStats(userID string) (*PlayerStats, error) {
+	stats := &PlayerStats{}
+	err := p.mm.KV.Get(botStatsKey(userID), stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// updateBotStats atomically applies update to the stats of userID in games
+// against the bot.
+func (p *Plugin) updateBotStats(userID string, update func(stats *PlayerStats)) error {
+	return p.mm.KV.SetAtomicWithRetries(botStatsKey(userID), func(oldValue []byte) (interface{}, error) {
+		stats := &PlayerStats{}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, stats); err != nil {
+				return nil, err
+			}
+		}
+
+		update(stats)
+
+		return stats, nil
+	})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChooseBotCard(t *testing.T) {
+	newGame := func() *Game {
+		return &Game{
+			CardValues:  []string{"joker", "heartsAce", "joker", "heartsAce", "clubsAce", "clubsAce"},
+			CardFlipped: make([]bool, 6),
+			Rows:        2,
+			Columns:     3,
+			LastFlipped: -1,
+			Players:     []string{"user1", "bot"},
+			Started:     true,
+			Scores:      map[string]int{"user1": 0, "bot": 0},
+		}
+	}
+	perfect, _ := GetBotSkill(BotMemoryPerfect)
+	rng := rand.New(rand.NewSource(1))
+
+	t.Run("perfect memory completes known pairs", func(t *testing.T) {
+		game := newGame()
+		_, err := game.Flip("user1", 0)
+		require.NoError(t, err)
+		_, err = game.Flip("user1", 1)
+		require.NoError(t, err)
+		_, err = game.Flip("bot", 3)
+		require.NoError(t, err)
+
+		game.rememberCards("bot", perfect, rng)
+		assert.Equal(t, 1, chooseBotCard(game, rng))
+
+		_, err = game.Flip("bot", 1)
+		require.NoError(t, err)
+		_, err = game.Flip("bot", 2)
+		require.NoError(t, err)
+		game.rememberCards("bot", perfect, rng)
+		assert.Equal(t, 0, chooseBotCard(game, rng))
+	})
+
+	t.Run("no memory flips unknown cards", func(t *testing.T) {
+		game := newGame()
+		_, err := game.Flip("user1", 0)
+		require.NoError(t, err)
+		_, err = game.Flip("user1", 1)
+		require.NoError(t, err)
+
+		for i := 0; i < 20; i++ {
+			game.rememberCards("bot", BotSkill{}, rng)
+			index := chooseBotCard(game, rng)
+			assert.False(t, game.CardFlipped[index])
+		}
+	})
+}
+
+func TestRememberCards(t *testing.T) {
+	newGame := func() *Game {
+		return &Game{
+			CardValues:  []string{"joker", "heartsAce", "joker", "heartsAce", "clubsAce", "clubsAce"},
+			CardFlipped: make([]bool, 6),
+			Rows:        2,
+			Columns:     3,
+			LastFlipped: -1,
+			Players:     []string{"user1", "bot"},
+			Started:     true,
+			Scores:      map[string]int{"user1": 0, "bot": 0},
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+
+	t.Run("memory lasts across turns", func(t *testing.T) {
+		game := newGame()
+		_, err := game.Flip("user1", 0)
+		require.NoError(t, err)
+		_, err = game.Flip("user1", 1)
+		require.NoError(t, err)
+
+		skill := BotSkill{Accuracy: 0.5}
+		game.rememberCards("bot", skill, rng)
+		known := append([]int{}, game.BotKnownCards...)
+		assert.Equal(t, 2, game.BotSeenEvents)
+
+		for i := 0; i < 20; i++ {
+			game.rememberCards("bot", skill, rng)
+			assert.Equal(t, known, game.BotKnownCards)
+		}
+	})
+
+	t.Run("the bot remembers its own cards", func(t *testing.T) {
+		game := newGame()
+		game.Turn = 1
+		_, err := game.Flip("bot", 4)
+		require.NoError(t, err)
+
+		game.rememberCards("bot", BotSkill{}, rng)
+		assert.Equal(t, []int{4}, game.BotKnownCards)
+	})
+
+	t.Run("cards are forgotten as the game goes on", func(t *testing.T) {
+		game := newGame()
+		_, err := game.Flip("user1", 0)
+		require.NoError(t, err)
+		game.rememberCards("bot", BotSkill{Accuracy: 1, Forgetting: 1}, rng)
+		assert.Equal(t, []int{0}, game.BotKnownCards)
+
+		_, err = game.Flip("user1", 1)
+		require.NoError(t, err)
+		game.rememberCards("bot", BotSkill{Accuracy: 1, Forgetting: 1}, rng)
+		assert.Equal(t, []int{1}, game.BotKnownCards)
+	})
+
+	t.Run("matched cards are forgotten", func(t *testing.T) {
+		game := newGame()
+		_, err := game.Flip("user1", 0)
+		require.NoError(t, err)
+		_, err = game.Flip("user1", 2)
+		require.NoError(t, err)
+
+		game.rememberCards("bot", BotSkill{Accuracy: 1}, rng)
+		assert.Empty(t, game.BotKnownCards)
+	})
+}
+
+func TestBotGameStats(t *testing.T) {
+	p := newTestPlugin(t)
+	game := newTestGame(t, p)
+	game.Players = []string{"user1", "bot"}
+	game.Scores = map[string]int{"user1": 0, "bot": 0}
+	game.BotMemory = BotMemoryPerfect
+	require.NoError(t, p.setGame(game))
+
+	_, err := p.resignGame(game.GID, "user1")
+	require.NoError(t, err)
+
+	botStats, err := p.getBotStats("user1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, botStats.GamesPlayed)
+	assert.Equal(t, 1, botStats.Losses)
+
+	stats, err := p.getPlayerStats("user1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.GamesPlayed)
+
+	leaderboard, err := p.getLeaderboard(LeaderboardPeriodAll)
+	require.NoError(t, err)
+	assert.Empty(t, leaderboard)
+}
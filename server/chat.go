@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	chatFlip = "flip"
+	// chatMaxCards is the number of cards a single message can flip, a whole
+	// turn.
+	chatMaxCards = 2
+
+	emojiFaceDown = ":white_large_square:"
+	emojiMatched  = ":white_check_mark:"
+	emojiFaceUp   = ":eyes:"
+	emojiCorner   = ":black_large_square:"
+)
+
+// chatCoordinatesRegexp matches a card named by its column letter and row
+// number, like b2.
+var chatCoordinatesRegexp = regexp.MustCompile(`^([a-z])([1-9][0-9]?)$`) //nolint: gochecknoglobals
+
+// emojiRows holds the emojis labelling the rows of the board, as the largest
+// board has six of them.
+var emojiRows = []string{":one:", ":two:", ":three:", ":four:", ":five:", ":six:"} //nolint: gochecknoglobals
+
+// ChatCard is a card named in a chat message, either by its Number, starting
+// at 1 as on the buttons of the board post, or by its Column and Row,
+// starting at 0.
+type ChatCard struct {
+	Number int
+	Column int
+	Row    int
+}
+
+// Index returns the index of the card on the board of game, or -1 if the
+// board has no such card.
+func (c ChatCard) Index(game *Game) int {
+	if c.Number > 0 {
+		return c.Number - 1
+	}
+
+	if c.Column >= game.Columns || c.Row >= game.Rows {
+		return -1
+	}
+
+	return c.Row*game.Columns + c.Column
+}
+
+// ParseChatMove parses a message like "flip 3 7" or "b2 c4" into the cards it
+// flips. It returns false if the message is not a move.
+func ParseChatMove(message string) ([]ChatCard, bool) {
+	fields := strings.Fields(strings.ToLower(message))
+	if len(fields) > 0 && fields[0] == chatFlip {
+		fields = fields[1:]
+		if len(fields) == 0 || len(fields) > chatMaxCards {
+			return nil, false
+		}
+
+		cards := []ChatCard{}
+		for _, field := range fields {
+			number, err := strconv.Atoi(field)
+			if err != nil || number < 1 {
+				return nil, false
+			}
+			cards = append(cards, ChatCard{Number: number})
+		}
+		return cards, true
+	}
+
+	if len(fields) == 0 || len(fields) > chatMaxCards {
+		return nil, false
+	}
+
+	cards := []ChatCard{}
+	for _, field := range fields {
+		match := chatCoordinatesRegexp.FindStringSubmatch(field)
+		if match == nil {
+			return nil, false
+		}
+		row, _ := strconv.Atoi(match[2])
+		cards = append(cards, ChatCard{Column: int(match[1][0] - 'a'), Row: row - 1})
+	}
+
+	return cards, true
+}
+
+// cardCoordinates returns the column letter and row number of the card at
+// index of game, like b2.
+func cardCoordinates(game *Game, index int) string {
+	return fmt.Sprintf("%c%d", 'a'+index%game.Columns, index/game.Columns+1)
+}
+
+// emojiBoard renders the board of game as a grid of emojis, with the column
+// letters and row numbers used to name the cards.
+func emojiBoard(game *Game) string {
+	text := emojiCorner
+	for column := 0; column < game.Columns; column++ {
+		text += fmt.Sprintf(":regional_indicator_%c:", 'a'+column)
+	}
+	text += "\n"
+
+	for row := 0; row < game.Rows; row++ {
+		text += emojiRows[row]
+		for column := 0; column < game.Columns; column++ {
+			index := row*game.Columns + column
+			switch {
+			case index == game.LastFlipped:
+				text += emojiFaceUp
+			case game.CardFlipped[index]:
+				text += emojiMatched
+			default:
+				text += emojiFaceDown
+			}
+		}
+		text += "\n"
+	}
+
+	return text
+}
+
+// MessageHasBeenPosted plays the moves typed by players in channels with a
+// game, and answers them with the board.
+func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	if post.UserId == p.BotUserID || post.IsSystemMessage() {
+		return
+	}
+
+	cards, ok := ParseChatMove(post.Message)
+	if !ok {
+		return
+	}
+
+	games, err := p.getChannelGames(post.ChannelId)
+	if err != nil {
+		p.mm.Log.Warn("Cannot get channel games", "channelID", post.ChannelId, "err", err.Error())
+		return
+	}
+
+	playing := []*Game{}
+	waiting := false
+	for _, game := range games {
+		if !game.Started || game.Over || !game.IsPlayer(post.UserId) {
+			continue
+		}
+		if game.CurrentPlayer() != post.UserId {
+			waiting = true
+			continue
+		}
+		playing = append(playing, game)
+	}
+
+	switch {
+	case len(playing) == 0 && waiting:
+		p.replyToMove(post, "It is not your turn.")
+	case len(playing) > 1:
+		p.replyToMove(post, "It is your turn in several games of this channel. Play them from the board posts or the memory game panel instead.")
+	case len(playing) == 1:
+		p.replyToMove(post, p.playChatMove(playing[0], post.UserId, cards))
+	}
+}
+
+// playChatMove flips cards in game on behalf of userID and returns the reply
+// of the bot. It stops at the first card that cannot be flipped.
+func (p *Plugin) playChatMove(game *Game, userID string, cards []ChatCard) string {
+	flipped := []string{}
+	values := []string{}
+	failure := ""
+	for _, card := range cards {
+		index := card.Index(game)
+		updated, value, err := p.flipCard(game.GID, userID, index, game.Seq)
+		if err != nil {
+			failure = gameErrorMessage(err, "Cannot flip the card.")
+			break
+		}
+
+		game = updated
+		values = append(values, value)
+		flipped = append(flipped, fmt.Sprintf("%s (%s)", CardLabel(value), cardCoordinates(game, index)))
+		p.sendFlipWebsocket(game, userID, index, value)
+	}
+
+	text := ""
+	if len(flipped) > 0 {
+		text += fmt.Sprintf("@%s turned over %s.", p.getUsername(userID), strings.Join(flipped, " and "))
+		if len(values) == chatMaxCards && values[0] == values[1] {
+			text += " It is a pair!"
+		}
+		text += "\n"
+		p.sendResyncWebsocket(userID, game)
+		p.startBotTurn(game)
+	}
+	if failure != "" {
+		text += failure + "\n"
+	}
+
+	return text + p.describeStatus(game) + "\n\n" + emojiBoard(game)
+}
+
+// replyToMove answers the chat message post with message from the bot, in
+// the same thread.
+func (p *Plugin) replyToMove(post *model.Post, message string) {
+	err := p.mm.Post.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: post.ChannelId,
+		RootId:    post.RootId,
+		Message:   message,
+	})
+	if err != nil {
+		p.mm.Log.Warn("Cannot answer chat move", "channelID", post.ChannelId, "err", err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChatMove(t *testing.T) {
+	for message, expected := range map[string][]ChatCard{
+		"flip 3 7":   {{Number: 3}, {Number: 7}},
+		"Flip 12":    {{Number: 12}},
+		"b2 c4":      {{Column: 1, Row: 1}, {Column: 2, Row: 3}},
+		" A1 ":       {{Column: 0, Row: 0}},
+		"flip":       nil,
+		"flip 0":     nil,
+		"flip 1 2 3": nil,
+		"b2 c4 d1":   nil,
+		"hello":      nil,
+		"b2 later":   nil,
+		"":           nil,
+	} {
+		cards, ok := ParseChatMove(message)
+		assert.Equal(t, expected != nil, ok, message)
+		assert.Equal(t, expected, cards, message)
+	}
+
+	game := &Game{Rows: 4, Columns: 3}
+	assert.Equal(t, 4, ChatCard{Column: 1, Row: 1}.Index(game))
+	assert.Equal(t, -1, ChatCard{Column: 3, Row: 0}.Index(game))
+	assert.Equal(t, 6, ChatCard{Number: 7}.Index(game))
+	assert.Equal(t, "b2", cardCoordinates(game, 4))
+}
+
+func TestMessageHasBeenPosted(t *testing.T) {
+	p := newTestPlugin(t)
+	api := p.API.(*fakeAPI)
+	game := newTestGame(t, p)
+	game.ChannelID = "channel"
+	require.NoError(t, p.setGame(game))
+	require.NoError(t, p.addChannelGame("channel", game.GID))
+
+	p.MessageHasBeenPosted(nil, &model.Post{UserId: "user1", ChannelId: "channel", Message: "hello"})
+	assert.Empty(t, api.posts)
+
+	p.MessageHasBeenPosted(nil, &model.Post{UserId: "user2", ChannelId: "channel", Message: "flip 1 2"})
+	require.Len(t, api.posts, 1)
+	assert.Equal(t, "It is not your turn.", api.posts[0].Message)
+
+	p.MessageHasBeenPosted(nil, &model.Post{UserId: "user1", ChannelId: "channel", Message: "a1 b1", RootId: "root"})
+	require.Len(t, api.posts, 2)
+	assert.Equal(t, "root", api.posts[1].RootId)
+	assert.Contains(t, api.posts[1].Message, "@user1 turned over Joker (a1) and Joker (b1). It is a pair!")
+	assert.Contains(t, api.posts[1].Message, emojiMatched+emojiMatched+"\n")
+
+	p.MessageHasBeenPosted(nil, &model.Post{UserId: "user1", ChannelId: "channel", Message: "flip 3 3"})
+	require.Len(t, api.posts, 3)
+	assert.Contains(t, api.posts[2].Message, "This card is already flipped.")
+
+	stored, err := p.getGame(game.GID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Scores["user1"])
+	assert.Equal(t, 2, stored.LastFlipped)
+}
@@ -0,0 +1,788 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	commandStart       = "start"
+	commandJoin        = "join"
+	commandLeave       = "leave"
+	commandBegin       = "begin"
+	commandResign      = "resign"
+	commandDraw        = "draw"
+	commandRematch     = "rematch"
+	commandPractice    = "practice"
+	commandRecords     = "records"
+	commandDaily       = "daily"
+	commandStatus      = "status"
+	commandStats       = "stats"
+	commandLeaderboard = "leaderboard"
+	commandHelp        = "help"
+
+	drawOffer  = "offer"
+	drawAccept = "accept"
+
+	dailyResults = "results"
+
+	leaderboardSize      = 10
+	leaderboardScopeTeam = "team"
+)
+
+const commandHelpText = "###### Memory game - Slash command help\n" +
+	"* `/memory start [@username] [easy|medium|hard]` - Start a memory game with a user, against @memory, or in the current channel\n" +
+	"* `/memory join [gameID]` - Join a game in the current channel before it begins\n" +
+	"* `/memory leave [gameID]` - Leave a game in the current channel before it begins\n" +
+	"* `/memory begin [gameID]` - Begin a game you started in the current channel\n" +
+	"* `/memory resign [gameID]` - Give up a game in the current channel\n" +
+	"* `/memory draw [offer|accept] [gameID]` - Offer or accept a draw in a game in the current channel\n" +
+	"* `/memory rematch [gameID]` - Play a finished game again with the same players, your last game by default\n" +
+	"* `/memory practice [easy|medium|hard]` - Clear a board alone in the current channel, in as few moves as possible\n" +
+	"* `/memory daily` - Play the daily challenge, the same board for everyone once per day\n" +
+	"* `/memory status [gameID]` - Show the status of the games in the current channel\n" +
+	"* `/memory stats [@username]` - Show the stats of a user\n" +
+	"* `/memory leaderboard [all|month|week] [team]` - Show the players with the most wins, optionally only in the current team\n" +
+	"* `/memory records [easy|medium|hard]` - Show your personal bests and the best practice results\n" +
+	"* `/memory daily results [YYYY-MM-DD]` - Show the ranking of the daily challenge and your streak\n" +
+	"* `/memory help` - Show this help text\n" +
+	"\nThe game ID is only needed when the channel has several matching games.\n" +
+	"On your turn, you can also type the cards to turn over in the channel of the game, by number like `flip 3 7` or by column and row like `b2 c4`."
+
+func createMemoryCommand() *model.Command {
+	return &model.Command{
+		Trigger:          CommandTrigger,
+		AutoComplete:     true,
+		AutoCompleteDesc: "Available commands: start, join, leave, begin, resign, draw, rematch, practice, daily, status, stats, leaderboard, records, help",
+		AutoCompleteHint: "[command]",
+		AutocompleteData: getAutocompleteData(),
+	}
+}
+
+func getAutocompleteData() *model.AutocompleteData {
+	memory := model.NewAutocompleteData(CommandTrigger, "[command]", "Available commands: start, join, leave, begin, resign, draw, rematch, practice, daily, status, stats, leaderboard, records, help")
+
+	start := model.NewAutocompleteData(commandStart, "[@username] [difficulty]", "Start a memory game with a user, or in the current channel")
+	start.AddTextArgument("User to play against, or @memory to play against the bot. Leave empty to play in the current channel", "[@username]", "")
+	start.AddStaticListArgument("Board size", false, difficultyListItems())
+	memory.AddCommand(start)
+
+	join := model.NewAutocompleteData(commandJoin, "[gameID]", "Join a game in the current channel before it begins")
+	join.AddTextArgument("Game to join", "[gameID]", "")
+	memory.AddCommand(join)
+
+	leave := model.NewAutocompleteData(commandLeave, "[gameID]", "Leave a game in the current channel before it begins")
+	leave.AddTextArgument("Game to leave", "[gameID]", "")
+	memory.AddCommand(leave)
+
+	begin := model.NewAutocompleteData(commandBegin, "[gameID]", "Begin a game you started in the current channel")
+	begin.AddTextArgument("Game to begin", "[gameID]", "")
+	memory.AddCommand(begin)
+
+	resign := model.NewAutocompleteData(commandResign, "[gameID]", "Give up a game in the current channel")
+	resign.AddTextArgument("Game to give up", "[gameID]", "")
+	memory.AddCommand(resign)
+
+	draw := model.NewAutocompleteData(commandDraw, "[offer|accept] [gameID]", "Offer or accept a draw in a game in the current channel")
+	drawOffer := model.NewAutocompleteData(drawOffer, "[gameID]", "Offer the other players to end the game in a draw")
+	drawOffer.AddTextArgument("Game to end", "[gameID]", "")
+	draw.AddCommand(drawOffer)
+	drawAccept := model.NewAutocompleteData(drawAccept, "[gameID]", "Accept the draw offered by another player")
+	drawAccept.AddTextArgument("Game to end", "[gameID]", "")
+	draw.AddCommand(drawAccept)
+	memory.AddCommand(draw)
+
+	rematch := model.NewAutocompleteData(commandRematch, "[gameID]", "Play a finished game again with the same players")
+	rematch.AddTextArgument("Finished game to play again. Defaults to your last game", "[gameID]", "")
+	memory.AddCommand(rematch)
+
+	practice := model.NewAutocompleteData(commandPractice, "[difficulty]", "Clear a board alone in the current channel")
+	practice.AddStaticListArgument("Board size", false, difficultyListItems())
+	memory.AddCommand(practice)
+
+	daily := model.NewAutocompleteData(commandDaily, "[results]", "Play the daily challenge, the same board for everyone once per day")
+	results := model.NewAutocompleteData(dailyResults, "[YYYY-MM-DD]", "Show the ranking of the daily challenge and your streak")
+	results.AddTextArgument("Day of the challenge. Defaults to today", "[YYYY-MM-DD]", "")
+	daily.AddCommand(results)
+	memory.AddCommand(daily)
+
+	status := model.NewAutocompleteData(commandStatus, "[gameID]", "Show the status of the games in the current channel")
+	status.AddTextArgument("Game to show", "[gameID]", "")
+	memory.AddCommand(status)
+
+	stats := model.NewAutocompleteData(commandStats, "[@username]", "Show the stats of a user")
+	stats.AddTextArgument("User to show the stats of. Defaults to yourself", "[@username]", "")
+	memory.AddCommand(stats)
+
+	leaderboard := model.NewAutocompleteData(commandLeaderboard, "[period] [team]", "Show the players with the most wins")
+	leaderboard.AddStaticListArgument("Period to rank", false, []model.AutocompleteListItem{
+		{Item: LeaderboardPeriodAll, HelpText: "All time"},
+		{Item: LeaderboardPeriodMonth, HelpText: "This month"},
+		{Item: LeaderboardPeriodWeek, HelpText: "This week"},
+	})
+	leaderboard.AddStaticListArgument("Scope of the ranking", false, []model.AutocompleteListItem{
+		{Item: leaderboardScopeTeam, HelpText: "Only rank the members of the current team"},
+	})
+	memory.AddCommand(leaderboard)
+
+	records := model.NewAutocompleteData(commandRecords, "[difficulty]", "Show your personal bests and the best practice results")
+	records.AddStaticListArgument("Board size", false, difficultyListItems())
+	memory.AddCommand(records)
+
+	help := model.NewAutocompleteData(commandHelp, "", "Show the help text")
+	memory.AddCommand(help)
+
+	return memory
+}
+
+func difficultyListItems() []model.AutocompleteListItem {
+	return []model.AutocompleteListItem{
+		{Item: DifficultyEasy, HelpText: "12 cards"},
+		{Item: DifficultyMedium, HelpText: "20 cards"},
+		{Item: DifficultyHard, HelpText: "36 cards"},
+	}
+}
+
+// ExecuteCommand executes the /memory slash command.
+func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	split := strings.Fields(args.Command)
+	if len(split) < 2 {
+		return p.commandResponse(commandHelpText), nil
+	}
+
+	params := split[2:]
+
+	switch split[1] {
+	case commandStart:
+		return p.runStartCommand(args, params), nil
+	case commandJoin:
+		return p.runLobbyCommand(args, params, func(game *Game) bool {
+			return !game.Started && !game.IsPlayer(args.UserId)
+		}, p.joinGame, "You joined the game."), nil
+	case commandLeave:
+		return p.runLobbyCommand(args, params, func(game *Game) bool {
+			return !game.Started && game.IsPlayer(args.UserId)
+		}, p.leaveGame, "You left the game."), nil
+	case commandBegin:
+		return p.runLobbyCommand(args, params, func(game *Game) bool {
+			return !game.Started && game.Owner == args.UserId
+		}, p.beginGame, "The game has begun."), nil
+	case commandResign:
+		return p.runResignCommand(args, params), nil
+	case commandDraw:
+		return p.runDrawCommand(args, params), nil
+	case commandRematch:
+		return p.runRematchCommand(args, params), nil
+	case commandPractice:
+		return p.runPracticeCommand(args, params), nil
+	case commandRecords:
+		return p.runRecordsCommand(args, params), nil
+	case commandDaily:
+		return p.runDailyCommand(args, params), nil
+	case commandStatus:
+		return p.runStatusCommand(args, params), nil
+	case commandStats:
+		return p.runStatsCommand(args, params), nil
+	case commandLeaderboard:
+		return p.runLeaderboardCommand(args, params), nil
+	case commandHelp:
+		return p.commandResponse(commandHelpText), nil
+	default:
+		return p.commandResponse(fmt.Sprintf("Unknown command `%s`.\n%s", split[1], commandHelpText)), nil
+	}
+}
+
+func (p *Plugin) commandResponse(text string) *model.CommandResponse {
+	return &model.CommandResponse{
+		ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL,
+		Text:         text,
+	}
+}
+
+func (p *Plugin) getUserFromMention(mention string) (*model.User, error) {
+	return p.mm.User.GetByUsername(strings.TrimPrefix(mention, "@"))
+}
+
+func (p *Plugin) runStartCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	var mention, difficulty string
+	for _, param := range params {
+		switch {
+		case strings.HasPrefix(param, "@") && mention == "":
+			mention = param
+		case difficulty == "":
+			difficulty = param
+		default:
+			return p.commandResponse("Usage: `/memory start [@username] [easy|medium|hard]`")
+		}
+	}
+
+	if _, ok := GetBoardSize(difficulty); !ok {
+		return p.commandResponse(fmt.Sprintf("Unknown difficulty `%s`. Use easy, medium or hard.", difficulty))
+	}
+
+	var c *model.Channel
+	var err error
+	if mention == "" {
+		c, err = p.mm.Channel.Get(args.ChannelId)
+		if err != nil {
+			p.mm.Log.Debug("Cannot get channel", "err", err)
+			return p.commandResponse("Cannot get the current channel.")
+		}
+	} else {
+		var opponent *model.User
+		opponent, err = p.getUserFromMention(mention)
+		if err != nil {
+			return p.commandResponse(fmt.Sprintf("Cannot find user %s.", mention))
+		}
+
+		if opponent.Id == args.UserId {
+			return p.commandResponse("You cannot play against yourself.")
+		}
+
+		c, err = p.mm.Channel.GetDirect(args.UserId, opponent.Id)
+		if err != nil {
+			p.mm.Log.Debug("Cannot get direct channel", "err", err)
+			return p.commandResponse("Cannot open a direct message with that user.")
+		}
+	}
+
+	if c.Type == model.CHANNEL_DIRECT && c.GetOtherUserIdForDM(args.UserId) == args.UserId {
+		return p.commandResponse("You cannot play against yourself.")
+	}
+
+	var game *Game
+
+	if c.Type == model.CHANNEL_DIRECT && c.GetOtherUserIdForDM(args.UserId) != p.BotUserID {
+		_, err = p.inviteToGame(args.UserId, c, difficulty)
+	} else {
+		game, err = p.startGame(args.UserId, c, difficulty)
+	}
+	switch {
+	case errors.Is(err, ErrChannelTypeDisabled):
+		return p.commandResponse("Memory games are disabled in this type of channel.")
+	case errors.Is(err, ErrTooManyGames):
+		return p.commandResponse(fmt.Sprintf("Players cannot take part in more than %d unfinished games.", p.getConfiguration().MaxGamesPerUser))
+	case err != nil:
+		p.mm.Log.Debug("Cannot start game", "err", err)
+		return p.commandResponse("Cannot start the game.")
+	}
+
+	if game == nil {
+		return p.commandResponse(fmt.Sprintf("Invited @%s to a memory game. The game starts once they accept.", p.getUsername(c.GetOtherUserIdForDM(args.UserId))))
+	}
+	if game.BotMemory != "" {
+		return p.commandResponse(fmt.Sprintf("Started a memory game against @%s. Open your direct message with the bot to play.", p.getUsername(p.BotUserID)))
+	}
+
+	return p.commandResponse(fmt.Sprintf("Game `%s` created. Begin it with `/memory begin` once other members join.", game.GID))
+}
+
+// findGame returns the game of the current channel a command refers to. The
+// game ID can be left out when a single game of the channel matches filter.
+// If the game cannot be found, the response to send back is returned instead.
+func (p *Plugin) findGame(args *model.CommandArgs, params []string, filter func(game *Game) bool) (*Game, *model.CommandResponse) {
+	if len(params) > 1 {
+		return nil, p.commandResponse("Too many arguments. Only a game ID is expected.")
+	}
+
+	if len(params) == 1 {
+		game, err := p.getGame(params[0])
+		if err != nil || game.ChannelID != args.ChannelId {
+			return nil, p.commandResponse(fmt.Sprintf("There is no game `%s` in this channel.", params[0]))
+		}
+		return game, nil
+	}
+
+	games, err := p.getChannelGames(args.ChannelId)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get channel games", "err", err)
+		return nil, p.commandResponse("Cannot get the games of this channel.")
+	}
+
+	matching := []*Game{}
+	for _, game := range games {
+		if filter(game) {
+			matching = append(matching, game)
+		}
+	}
+
+	switch len(matching) {
+	case 0:
+		return nil, p.commandResponse("There is no matching game in this channel. Check `/memory status`.")
+	case 1:
+		return matching[0], nil
+	}
+
+	text := "Several games match. Add the ID of the game to the command:\n"
+	for _, game := range matching {
+		text += fmt.Sprintf("* `%s`: %s\n", game.GID, p.describePlayers(game))
+	}
+
+	return nil, p.commandResponse(text)
+}
+
+// describePlayers lists the players of game as mentions.
+func (p *Plugin) describePlayers(game *Game) string {
+	return "@" + strings.Join(p.getUsernames(game.Players), ", @")
+}
+
+func (p *Plugin) runLobbyCommand(args *model.CommandArgs, params []string, filter func(game *Game) bool, action func(gID, userID string) (*Game, error), success string) *model.CommandResponse {
+	game, resp := p.findGame(args, params, filter)
+	if resp != nil {
+		return resp
+	}
+
+	game, err := action(game.GID, args.UserId)
+	switch {
+	case errors.Is(err, ErrGameNotFound):
+		return p.commandResponse("The game does not exist anymore.")
+	case errors.Is(err, ErrGameStarted):
+		return p.commandResponse("The game has already begun.")
+	case errors.Is(err, ErrAlreadyJoined):
+		return p.commandResponse("You already joined the game.")
+	case errors.Is(err, ErrGameFull):
+		return p.commandResponse(fmt.Sprintf("The game already has %d players.", MaxPlayers))
+	case errors.Is(err, ErrTooManyGames):
+		return p.commandResponse(fmt.Sprintf("You cannot take part in more than %d unfinished games.", p.getConfiguration().MaxGamesPerUser))
+	case errors.Is(err, ErrNotAPlayer):
+		return p.commandResponse("You are not playing the game.")
+	case errors.Is(err, ErrNotOwner):
+		return p.commandResponse("Only the player that started the game can begin it.")
+	case errors.Is(err, ErrNotEnoughPlayers):
+		return p.commandResponse(fmt.Sprintf("At least %d players are needed to begin the game.", MinPlayers))
+	case err != nil:
+		p.mm.Log.Debug("Cannot update players", "err", err)
+		return p.commandResponse("Cannot update the game.")
+	}
+
+	p.sendResyncToPlayers(game, args.UserId)
+
+	return p.commandResponse(success)
+}
+
+func (p *Plugin) runResignCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	game, resp := p.findGame(args, params, func(game *Game) bool {
+		return game.Started && game.IsPlayer(args.UserId)
+	})
+	if resp != nil {
+		return resp
+	}
+
+	game, err := p.resignGame(game.GID, args.UserId)
+	switch {
+	case errors.Is(err, ErrGameNotFound):
+		return p.commandResponse("The game does not exist anymore.")
+	case errors.Is(err, ErrNotAPlayer):
+		return p.commandResponse("You are not playing the game.")
+	case errors.Is(err, ErrGameNotStarted):
+		return p.commandResponse("The game has not begun yet. Use `/memory leave` instead.")
+	case errors.Is(err, ErrGameOver):
+		return p.commandResponse("This game is already over.")
+	case err != nil:
+		p.mm.Log.Debug("Cannot resign", "err", err)
+		return p.commandResponse("Cannot resign the game.")
+	}
+
+	p.sendResyncToPlayers(game, args.UserId)
+
+	return p.commandResponse("You resigned the game.")
+}
+
+func (p *Plugin) runRematchCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	if len(params) > 1 {
+		return p.commandResponse("Too many arguments. Only a game ID is expected.")
+	}
+
+	var gID string
+	if len(params) == 1 {
+		gID = params[0]
+	} else {
+		games, err := p.getHistory(args.UserId, 0, 1)
+		if err != nil {
+			p.mm.Log.Debug("Cannot get history", "err", err)
+			return p.commandResponse("Cannot get your last game.")
+		}
+		if len(games) == 0 {
+			return p.commandResponse("You have not finished any game yet.")
+		}
+		gID = games[0].GID
+	}
+
+	game, series, err := p.rematchGame(gID, args.UserId)
+	switch {
+	case errors.Is(err, ErrGameNotFound):
+		return p.commandResponse(fmt.Sprintf("Cannot find game `%s`.", gID))
+	case errors.Is(err, ErrGameNotFinished):
+		return p.commandResponse("This game is not finished yet.")
+	case errors.Is(err, ErrNotAPlayer):
+		return p.commandResponse("You did not play this game.")
+	case errors.Is(err, ErrRematchPlayed):
+		return p.commandResponse("The rematch of this game was already played. Ask for a rematch of the last game instead.")
+	case errors.Is(err, ErrChannelTypeDisabled):
+		return p.commandResponse("Memory games are disabled in this type of channel.")
+	case errors.Is(err, ErrTooManyGames):
+		return p.commandResponse(fmt.Sprintf("Players cannot take part in more than %d unfinished games.", p.getConfiguration().MaxGamesPerUser))
+	case err != nil:
+		p.mm.Log.Debug("Cannot start rematch", "err", err)
+		return p.commandResponse("Cannot start the rematch.")
+	}
+
+	return p.commandResponse(fmt.Sprintf("Rematch `%s` started in ~%s. Series score: %s.", game.GID, p.getChannelName(game.ChannelID), p.formatSeries(series)))
+}
+
+func (p *Plugin) runDrawCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	if len(params) == 0 || (params[0] != drawOffer && params[0] != drawAccept) {
+		return p.commandResponse("Usage: `/memory draw [offer|accept] [gameID]`")
+	}
+
+	action, success := p.offerDraw, "You offered a draw."
+	if params[0] == drawAccept {
+		action, success = p.acceptDraw, "You accepted the draw."
+	}
+
+	game, resp := p.findGame(args, params[1:], func(game *Game) bool {
+		return game.Started && game.IsPlayer(args.UserId)
+	})
+	if resp != nil {
+		return resp
+	}
+
+	game, err := action(game.GID, args.UserId)
+	switch {
+	case errors.Is(err, ErrGameNotFound):
+		return p.commandResponse("The game does not exist anymore.")
+	case errors.Is(err, ErrNotAPlayer):
+		return p.commandResponse("You are not playing the game.")
+	case errors.Is(err, ErrGameOver):
+		return p.commandResponse("This game is already over.")
+	case errors.Is(err, ErrDrawOffered):
+		return p.commandResponse("A draw was already offered.")
+	case errors.Is(err, ErrNoDrawOffer):
+		return p.commandResponse("Nobody offered a draw.")
+	case err != nil:
+		p.mm.Log.Debug("Cannot update draw", "err", err)
+		return p.commandResponse("Cannot update the game.")
+	}
+
+	p.sendResyncToPlayers(game, args.UserId)
+
+	if game.Over {
+		return p.commandResponse("The game ended in a draw.")
+	}
+
+	return p.commandResponse(success)
+}
+
+func (p *Plugin) runStatusCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	games := []*Game{}
+	if len(params) > 0 {
+		game, resp := p.findGame(args, params, nil)
+		if resp != nil {
+			return resp
+		}
+		games = append(games, game)
+	} else {
+		var err error
+		games, err = p.getChannelGames(args.ChannelId)
+		if err != nil {
+			p.mm.Log.Debug("Cannot get channel games", "err", err)
+			return p.commandResponse("Cannot get the games of this channel.")
+		}
+	}
+
+	if len(games) == 0 {
+		return p.commandResponse("There is no game in progress in this channel.")
+	}
+
+	text := ""
+	for _, game := range games {
+		text += p.describeGame(game) + "\n"
+	}
+
+	return p.commandResponse(text)
+}
+
+// describeGame returns a Markdown summary of the state of game, with its
+// board once it has begun.
+func (p *Plugin) describeGame(game *Game) string {
+	text := fmt.Sprintf("###### Game `%s`\n", game.GID)
+	if !game.Started {
+		return text + p.describeStatus(game) + "\n" + p.describeScores(game)
+	}
+
+	text += p.markdownBoard(game)
+	text += fmt.Sprintf("\n%d pairs left to find.\n", countHiddenCards(game)/2)
+
+	return text
+}
+
+func (p *Plugin) runStatsCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	if len(params) > 1 {
+		return p.commandResponse("Usage: `/memory stats [@username]`")
+	}
+
+	userID := args.UserId
+	username := p.getUsername(userID)
+	if len(params) == 1 {
+		u, err := p.getUserFromMention(params[0])
+		if err != nil {
+			return p.commandResponse(fmt.Sprintf("Cannot find user %s.", params[0]))
+		}
+		userID = u.Id
+		username = u.Username
+	}
+
+	stats, err := p.getPlayerStats(userID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get stats", "err", err)
+		return p.commandResponse("Cannot get the stats.")
+	}
+
+	message := fmt.Sprintf("@%s has played %d memory games: %d wins, %d losses and %d draws. Best win streak: %d. Pairs matched: %d, at most %d in a row. Average moves per game: %.1f.",
+		username, stats.GamesPlayed, stats.Wins, stats.Losses, stats.Draws, stats.BestWinStreak, stats.PairsMatched, stats.LongestMatchStreak, stats.AverageMoves())
+
+	rating, err := p.getRating(userID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get rating", "err", err)
+		return p.commandResponse("Cannot get the stats.")
+	}
+	message += fmt.Sprintf(" Rating: %.0f", rating.Rating)
+	if rating.Provisional() {
+		message += " (provisional)"
+	}
+	message += "."
+
+	botStats, err := p.getBotStats(userID)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get stats against the bot", "err", err)
+		return p.commandResponse("Cannot get the stats.")
+	}
+	if botStats.GamesPlayed > 0 {
+		message += fmt.Sprintf(" Against @%s: %d games, %d wins, %d losses and %d draws.",
+			p.getUsername(p.BotUserID), botStats.GamesPlayed, botStats.Wins, botStats.Losses, botStats.Draws)
+	}
+
+	return p.commandResponse(message)
+}
+
+func (p *Plugin) runLeaderboardCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	usage := p.commandResponse("Usage: `/memory leaderboard [all|month|week] [team]`")
+	if len(params) > 2 {
+		return usage
+	}
+
+	period := LeaderboardPeriodAll
+	teamID := ""
+	for _, param := range params {
+		switch param {
+		case LeaderboardPeriodAll, LeaderboardPeriodMonth, LeaderboardPeriodWeek:
+			period = param
+		case leaderboardScopeTeam:
+			teamID = args.TeamId
+		default:
+			return usage
+		}
+	}
+
+	leaderboard, err := p.getRankedLeaderboard(period, teamID, 0, leaderboardSize)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get leaderboard", "err", err)
+		return p.commandResponse("Cannot get the leaderboard.")
+	}
+
+	if len(leaderboard.Entries) == 0 {
+		return p.commandResponse("Nobody has played a memory game yet.")
+	}
+
+	text := "###### Memory game leaderboard\n| # | Player | Wins | Games | Win rate | Best streak |\n|:-|:-|-:|-:|-:|-:|\n"
+	for _, entry := range leaderboard.Entries {
+		text += fmt.Sprintf("| %d | @%s | %d | %d | %.0f%% | %d |\n",
+			entry.Rank, entry.Username, entry.Wins, entry.GamesPlayed, entry.WinRate*100, entry.BestWinStreak)
+	}
+
+	return p.commandResponse(text)
+}
+
+// getUsernames returns the usernames of userIDs, in the same order.
+func (p *Plugin) getUsernames(userIDs []string) []string {
+	usernames := []string{}
+	for _, userID := range userIDs {
+		usernames = append(usernames, p.getUsername(userID))
+	}
+
+	return usernames
+}
+
+// getChannelName returns the name of channelID, or a placeholder if the
+// channel cannot be fetched.
+func (p *Plugin) getChannelName(channelID string) string {
+	c, err := p.mm.Channel.Get(channelID)
+	if err != nil {
+		return "unknown"
+	}
+
+	return c.Name
+}
+
+// getUsername returns the username of userID, or a placeholder if the user
+// cannot be fetched.
+func (p *Plugin) getUsername(userID string) string {
+	u, err := p.mm.User.Get(userID)
+	if err != nil {
+		return "unknown"
+	}
+
+	return u.Username
+}
+
+func (p *Plugin) runPracticeCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	if len(params) > 1 {
+		return p.commandResponse("Usage: `/memory practice [easy|medium|hard]`")
+	}
+
+	difficulty := ""
+	if len(params) == 1 {
+		difficulty = params[0]
+	}
+	if _, ok := GetBoardSize(difficulty); !ok {
+		return p.commandResponse(fmt.Sprintf("Unknown difficulty `%s`. Use easy, medium or hard.", difficulty))
+	}
+
+	c, err := p.mm.Channel.Get(args.ChannelId)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get channel", "err", err)
+		return p.commandResponse("Cannot get the current channel.")
+	}
+
+	game, err := p.startSoloGame(args.UserId, c, difficulty)
+	switch {
+	case errors.Is(err, ErrChannelTypeDisabled):
+		return p.commandResponse("Memory games are disabled in this type of channel.")
+	case errors.Is(err, ErrTooManyGames):
+		return p.commandResponse(fmt.Sprintf("Players cannot take part in more than %d unfinished games.", p.getConfiguration().MaxGamesPerUser))
+	case err != nil:
+		p.mm.Log.Debug("Cannot start solo game", "err", err)
+		return p.commandResponse("Cannot start the game.")
+	}
+
+	return p.commandResponse(fmt.Sprintf("Practice game `%s` started. Open the memory game of this channel to play.", game.GID))
+}
+
+func (p *Plugin) runRecordsCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	if len(params) > 1 {
+		return p.commandResponse("Usage: `/memory records [easy|medium|hard]`")
+	}
+
+	difficulty := p.getConfiguration().DefaultDifficulty
+	if len(params) == 1 {
+		difficulty = params[0]
+	}
+	if difficulty == "" {
+		difficulty = DifficultyEasy
+	}
+	if _, ok := GetBoardSize(difficulty); !ok {
+		return p.commandResponse(fmt.Sprintf("Unknown difficulty `%s`. Use easy, medium or hard.", difficulty))
+	}
+
+	bests, err := p.getSoloBests(args.UserId)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get solo bests", "err", err)
+		return p.commandResponse("Cannot get your personal bests.")
+	}
+
+	leaderboard, err := p.getRankedSoloLeaderboard(difficulty, 0, leaderboardSize)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get solo leaderboard", "err", err)
+		return p.commandResponse("Cannot get the practice leaderboard.")
+	}
+
+	text := "###### Your personal bests\n"
+	for _, d := range []string{DifficultyEasy, DifficultyMedium, DifficultyHard} {
+		if best := bests[d]; best != nil {
+			text += fmt.Sprintf("* %s: %d moves in %s\n", d, best.Moves, formatDuration(best.Duration))
+		} else {
+			text += fmt.Sprintf("* %s: not cleared yet\n", d)
+		}
+	}
+
+	text += fmt.Sprintf("###### Best practice results (%s)\n", difficulty)
+	if len(leaderboard.Entries) == 0 {
+		return p.commandResponse(text + "Nobody has cleared this board yet.")
+	}
+
+	text += "| # | Player | Moves | Time |\n|:-|:-|-:|-:|\n"
+	for _, entry := range leaderboard.Entries {
+		text += fmt.Sprintf("| %d | @%s | %d | %s |\n", entry.Rank, entry.Username, entry.Moves, formatDuration(entry.Duration))
+	}
+
+	return p.commandResponse(text)
+}
+
+func (p *Plugin) runDailyCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	if len(params) > 0 && params[0] == dailyResults {
+		return p.runDailyResultsCommand(args, params[1:])
+	}
+	if len(params) > 0 {
+		return p.commandResponse("Usage: `/memory daily [results [YYYY-MM-DD]]`")
+	}
+
+	game, err := p.startDailyChallenge(args.UserId, time.Now())
+	switch {
+	case errors.Is(err, ErrDailyPlayed):
+		return p.commandResponse("You already played today's challenge. See how you rank with `/memory daily results`.")
+	case errors.Is(err, ErrTooManyGames):
+		return p.commandResponse(fmt.Sprintf("Players cannot take part in more than %d unfinished games.", p.getConfiguration().MaxGamesPerUser))
+	case err != nil:
+		p.mm.Log.Debug("Cannot start daily challenge", "err", err)
+		return p.commandResponse("Cannot start the daily challenge.")
+	}
+
+	return p.commandResponse(fmt.Sprintf("Daily challenge `%s` started. Open the memory game of your direct messages with @%s to play.", game.GID, p.getUsername(p.BotUserID)))
+}
+
+func (p *Plugin) runDailyResultsCommand(args *model.CommandArgs, params []string) *model.CommandResponse {
+	if len(params) > 1 {
+		return p.commandResponse("Usage: `/memory daily results [YYYY-MM-DD]`")
+	}
+
+	date := GetDailyDate(time.Now())
+	if len(params) == 1 {
+		date = params[0]
+	}
+	if _, err := time.Parse(dailyDateLayout, date); err != nil {
+		return p.commandResponse(fmt.Sprintf("Unknown date `%s`. Use the YYYY-MM-DD format.", date))
+	}
+
+	resp, err := p.getDailyChallengeResponse(date, args.UserId, 0, leaderboardSize)
+	if err != nil {
+		p.mm.Log.Debug("Cannot get daily challenge", "err", err)
+		return p.commandResponse("Cannot get the daily challenge.")
+	}
+
+	text := fmt.Sprintf("###### Daily challenge of %s (%s)\n", resp.Date, resp.Difficulty)
+	switch {
+	case resp.Result != nil:
+		text += fmt.Sprintf("You cleared the board in %d moves and %s, ranked #%d of %d.\n", resp.Result.Moves, formatDuration(resp.Result.Duration), resp.Rank, resp.Total)
+	case resp.GID != "":
+		text += "You have not cleared the board.\n"
+	default:
+		text += "You did not play this challenge.\n"
+	}
+	text += fmt.Sprintf("Daily streak: %d, best: %d.\n", resp.Streak, resp.BestStreak)
+
+	if len(resp.Entries) == 0 {
+		return p.commandResponse(text + "Nobody has cleared this board yet.")
+	}
+
+	text += "| # | Player | Moves | Time |\n|:-|:-|-:|-:|\n"
+	for _, entry := range resp.Entries {
+		text += fmt.Sprintf("| %d | @%s | %d | %s |\n", entry.Rank, entry.Username, entry.Moves, formatDuration(entry.Duration))
+	}
+
+	return p.commandResponse(text)
+}
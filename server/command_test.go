@@ -0,0 +1,218 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func executeCommand(t *testing.T, p *Plugin, userID, channelID, command string) string {
+	t.Helper()
+
+	resp, appErr := p.ExecuteCommand(nil, &model.CommandArgs{
+		UserId:    userID,
+		ChannelId: channelID,
+		TeamId:    "team",
+		Command:   command,
+	})
+	require.Nil(t, appErr)
+	assert.Equal(t, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, resp.ResponseType)
+
+	return resp.Text
+}
+
+func newCommandTestPlugin(t *testing.T) *Plugin {
+	t.Helper()
+
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{
+		EnableDirectMessages: true,
+		EnablePublicChannels: true,
+		DefaultDifficulty:    DifficultyEasy,
+		BotMemory:            BotMemoryMedium,
+	})
+
+	return p
+}
+
+func TestHelpCommand(t *testing.T) {
+	p := newCommandTestPlugin(t)
+
+	assert.Equal(t, commandHelpText, executeCommand(t, p, "user1", "channel", "/memory"))
+	assert.Equal(t, commandHelpText, executeCommand(t, p, "user1", "channel", "/memory help"))
+
+	text := executeCommand(t, p, "user1", "channel", "/memory dance")
+	assert.True(t, strings.HasPrefix(text, "Unknown command `dance`.\n"))
+	assert.True(t, strings.HasSuffix(text, commandHelpText))
+
+	// The commands form a single list, followed by the notes.
+	lines := strings.Split(commandHelpText, "\n")
+	end := 1
+	for end < len(lines) && strings.HasPrefix(lines[end], "* ") {
+		end++
+	}
+	assert.Equal(t, "* `/memory help` - Show this help text", lines[end-1])
+	assert.Equal(t, "", lines[end])
+	assert.Contains(t, lines[end+1], "The game ID is only needed")
+	assert.Contains(t, lines[end+2], "`flip 3 7`")
+	assert.Len(t, lines, end+3)
+}
+
+func TestStartCommand(t *testing.T) {
+	p := newCommandTestPlugin(t)
+
+	assert.Equal(t, "Unknown difficulty `huge`. Use easy, medium or hard.", executeCommand(t, p, "user1", "channel", "/memory start huge"))
+	assert.Equal(t, "Usage: `/memory start [@username] [easy|medium|hard]`", executeCommand(t, p, "user1", "channel", "/memory start @user2 easy hard"))
+	assert.Equal(t, "Cannot find user @nobody.", executeCommand(t, p, "user1", "channel", "/memory start @nobody"))
+	assert.Equal(t, "You cannot play against yourself.", executeCommand(t, p, "user1", "channel", "/memory start @user1"))
+
+	text := executeCommand(t, p, "user1", "channel", "/memory start medium")
+	assert.Contains(t, text, "created. Begin it with `/memory begin`")
+	games, err := p.getChannelGames("channel")
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	assert.Equal(t, 5, games[0].Rows)
+
+	assert.Equal(t, "Invited @user2 to a memory game. The game starts once they accept.", executeCommand(t, p, "user1", "channel", "/memory start @user2"))
+	assert.Contains(t, executeCommand(t, p, "user1", "channel", "/memory start @bot"), "Started a memory game against @bot.")
+
+	p.setConfiguration(&configuration{})
+	assert.Equal(t, "Memory games are disabled in this type of channel.", executeCommand(t, p, "user1", "channel", "/memory start"))
+}
+
+func TestLobbyCommands(t *testing.T) {
+	p := newCommandTestPlugin(t)
+
+	assert.Equal(t, "There is no matching game in this channel. Check `/memory status`.", executeCommand(t, p, "user2", "channel", "/memory join"))
+
+	executeCommand(t, p, "user1", "channel", "/memory start")
+	games, err := p.getChannelGames("channel")
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	gID := games[0].GID
+
+	assert.Equal(t, "Too many arguments. Only a game ID is expected.", executeCommand(t, p, "user2", "channel", "/memory join a b"))
+	assert.Equal(t, "There is no game `other` in this channel.", executeCommand(t, p, "user2", "channel", "/memory join other"))
+	assert.Equal(t, "At least 2 players are needed to begin the game.", executeCommand(t, p, "user1", "channel", "/memory begin"))
+
+	assert.Equal(t, "You joined the game.", executeCommand(t, p, "user2", "channel", "/memory join "+gID))
+	assert.Equal(t, "You joined the game.", executeCommand(t, p, "user3", "channel", "/memory join"))
+	assert.Equal(t, "You left the game.", executeCommand(t, p, "user3", "channel", "/memory leave"))
+	assert.Equal(t, "There is no matching game in this channel. Check `/memory status`.", executeCommand(t, p, "user2", "channel", "/memory begin"))
+	assert.Equal(t, "The game has begun.", executeCommand(t, p, "user1", "channel", "/memory begin"))
+
+	game, err := p.getGame(gID)
+	require.NoError(t, err)
+	assert.True(t, game.Started)
+	assert.ElementsMatch(t, []string{"user1", "user2"}, game.Players)
+}
+
+func TestResignAndDrawCommands(t *testing.T) {
+	p := newCommandTestPlugin(t)
+	game := newTestGame(t, p)
+	game.ChannelID = "channel"
+	require.NoError(t, p.setGame(game))
+	require.NoError(t, p.addChannelGame("channel", game.GID))
+
+	assert.Equal(t, "Usage: `/memory draw [offer|accept] [gameID]`", executeCommand(t, p, "user1", "channel", "/memory draw"))
+	assert.Equal(t, "Usage: `/memory draw [offer|accept] [gameID]`", executeCommand(t, p, "user1", "channel", "/memory draw maybe"))
+	assert.Equal(t, "Nobody offered a draw.", executeCommand(t, p, "user2", "channel", "/memory draw accept"))
+	assert.Equal(t, "You offered a draw.", executeCommand(t, p, "user1", "channel", "/memory draw offer"))
+	assert.Equal(t, "A draw was already offered.", executeCommand(t, p, "user1", "channel", "/memory draw offer game"))
+	assert.Equal(t, "There is no matching game in this channel. Check `/memory status`.", executeCommand(t, p, "user3", "channel", "/memory resign"))
+	assert.Equal(t, "The game ended in a draw.", executeCommand(t, p, "user2", "channel", "/memory draw accept"))
+
+	game = newTestGame(t, p)
+	game.ChannelID = "channel"
+	require.NoError(t, p.setGame(game))
+	require.NoError(t, p.addChannelGame("channel", game.GID))
+
+	assert.Equal(t, "You resigned the game.", executeCommand(t, p, "user2", "channel", "/memory resign"))
+	archived, err := p.getArchivedGame(game.GID)
+	require.NoError(t, err)
+	assert.Equal(t, "user1", archived.Winner())
+}
+
+func TestRematchCommand(t *testing.T) {
+	p := newCommandTestPlugin(t)
+
+	assert.Equal(t, "You have not finished any game yet.", executeCommand(t, p, "user1", "user1__user2", "/memory rematch"))
+	assert.Equal(t, "Too many arguments. Only a game ID is expected.", executeCommand(t, p, "user1", "user1__user2", "/memory rematch a b"))
+	assert.Equal(t, "Cannot find game `other`.", executeCommand(t, p, "user1", "user1__user2", "/memory rematch other"))
+
+	game := newTestGame(t, p)
+	game.ChannelID = "user1__user2"
+	game.Rows, game.Columns = 4, 3
+	require.NoError(t, p.setGame(game))
+	assert.Equal(t, "This game is not finished yet.", executeCommand(t, p, "user1", "user1__user2", "/memory rematch game"))
+
+	_, err := p.resignGame(game.GID, "user2")
+	require.NoError(t, err)
+	assert.Equal(t, "You did not play this game.", executeCommand(t, p, "user3", "user1__user2", "/memory rematch game"))
+	assert.Contains(t, executeCommand(t, p, "user1", "user1__user2", "/memory rematch"), "Series score: @user1 1 - @user2 0.")
+}
+
+func TestPracticeAndRecordsCommands(t *testing.T) {
+	p := newCommandTestPlugin(t)
+
+	assert.Equal(t, "Usage: `/memory practice [easy|medium|hard]`", executeCommand(t, p, "user1", "channel", "/memory practice easy hard"))
+	assert.Equal(t, "Unknown difficulty `huge`. Use easy, medium or hard.", executeCommand(t, p, "user1", "channel", "/memory practice huge"))
+	assert.Contains(t, executeCommand(t, p, "user1", "channel", "/memory practice hard"), "Open the memory game of this channel to play.")
+
+	assert.Equal(t, "Usage: `/memory records [easy|medium|hard]`", executeCommand(t, p, "user1", "channel", "/memory records easy hard"))
+	assert.Equal(t, "Unknown difficulty `huge`. Use easy, medium or hard.", executeCommand(t, p, "user1", "channel", "/memory records huge"))
+	text := executeCommand(t, p, "user1", "channel", "/memory records")
+	assert.Contains(t, text, "* easy: not cleared yet")
+	assert.Contains(t, text, "Nobody has cleared this board yet.")
+}
+
+func TestDailyCommand(t *testing.T) {
+	p := newCommandTestPlugin(t)
+
+	assert.Equal(t, "Usage: `/memory daily [results [YYYY-MM-DD]]`", executeCommand(t, p, "user1", "channel", "/memory daily now"))
+	assert.Equal(t, "Usage: `/memory daily results [YYYY-MM-DD]`", executeCommand(t, p, "user1", "channel", "/memory daily results a b"))
+	assert.Equal(t, "Unknown date `yesterday`. Use the YYYY-MM-DD format.", executeCommand(t, p, "user1", "channel", "/memory daily results yesterday"))
+
+	assert.Contains(t, executeCommand(t, p, "user1", "channel", "/memory daily"), "Daily challenge")
+	assert.Contains(t, executeCommand(t, p, "user1", "channel", "/memory daily"), "You already played today's challenge.")
+}
+
+func TestStatusCommand(t *testing.T) {
+	p := newCommandTestPlugin(t)
+
+	assert.Equal(t, "There is no game in progress in this channel.", executeCommand(t, p, "user1", "channel", "/memory status"))
+
+	game := newTestGame(t, p)
+	game.ChannelID = "channel"
+	require.NoError(t, p.setGame(game))
+	require.NoError(t, p.addChannelGame("channel", game.GID))
+
+	text := executeCommand(t, p, "user1", "channel", "/memory status game")
+	assert.Contains(t, text, "###### Game `game`")
+	assert.Contains(t, text, "It is @user1's turn.")
+	assert.Contains(t, text, "2 pairs left to find.")
+	assert.Equal(t, text, executeCommand(t, p, "user1", "channel", "/memory status"))
+	assert.Equal(t, "There is no game `other` in this channel.", executeCommand(t, p, "user1", "channel", "/memory status other"))
+}
+
+func TestStatsAndLeaderboardCommands(t *testing.T) {
+	p := newCommandTestPlugin(t)
+
+	assert.Equal(t, "Usage: `/memory stats [@username]`", executeCommand(t, p, "user1", "channel", "/memory stats @user1 @user2"))
+	assert.Equal(t, "Cannot find user @nobody.", executeCommand(t, p, "user1", "channel", "/memory stats @nobody"))
+	assert.Contains(t, executeCommand(t, p, "user1", "channel", "/memory stats"), "@user1 has played 0 memory games")
+	assert.Contains(t, executeCommand(t, p, "user1", "channel", "/memory stats @user2"), "@user2 has played 0 memory games")
+
+	assert.Equal(t, "Usage: `/memory leaderboard [all|month|week] [team]`", executeCommand(t, p, "user1", "channel", "/memory leaderboard yearly"))
+	assert.Equal(t, "Usage: `/memory leaderboard [all|month|week] [team]`", executeCommand(t, p, "user1", "channel", "/memory leaderboard all team extra"))
+	assert.Equal(t, "Nobody has played a memory game yet.", executeCommand(t, p, "user1", "channel", "/memory leaderboard"))
+
+	game := newTestGame(t, p)
+	_, err := p.resignGame(game.GID, "user2")
+	require.NoError(t, err)
+	text := executeCommand(t, p, "user1", "channel", "/memory leaderboard week")
+	assert.Contains(t, text, "| 1 | @user1 | 1 | 1 | 100% | 1 |")
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"reflect"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
 )
 
@@ -18,6 +19,60 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	// TurnTimeoutMinutes is the time a player has to move. Zero disables turn
+	// timeouts.
+	TurnTimeoutMinutes int
+	// TurnTimeoutAction is what happens to players that run out of time, one
+	// of the TimeoutAction constants.
+	TurnTimeoutAction string
+	// AbandonedGameDays is the number of days without moves after which a game
+	// is removed. Zero keeps games forever.
+	AbandonedGameDays int
+	// DefaultDifficulty is the board size of games started without one.
+	DefaultDifficulty string
+	// MatchGrantsExtraTurn lets players keep the turn after matching a pair.
+	MatchGrantsExtraTurn bool
+	// MaxGamesPerUser is the number of unfinished games a user can take part
+	// in at once. Zero means no limit.
+	MaxGamesPerUser int
+	// BotMemory is how well the memory bot remembers revealed cards, one of
+	// the BotMemory constants.
+	BotMemory string
+	// DailyChallengeChannelID is the channel the results of the daily
+	// challenge are posted to. Empty disables posting them.
+	DailyChallengeChannelID string
+	// The types of channels games can be started in.
+	EnableDirectMessages  bool
+	EnableGroupMessages   bool
+	EnablePrivateChannels bool
+	EnablePublicChannels  bool
+	// EnableNotifications lets the bot send direct messages to players.
+	EnableNotifications bool
+	// EnableBadges grants badges through the badges plugin.
+	EnableBadges bool
+	// EnableBoardPosts makes the bot post the board of every game with a
+	// button per card, for clients that do not load the webapp plugin.
+	EnableBoardPosts bool
+	// EnableSummaryPosts makes the bot post the result of finished games with
+	// a picture of the final board and a rematch button.
+	EnableSummaryPosts bool
+}
+
+// IsChannelTypeEnabled returns whether games can be started in channels of
+// channelType.
+func (c *configuration) IsChannelTypeEnabled(channelType string) bool {
+	switch channelType {
+	case model.CHANNEL_DIRECT:
+		return c.EnableDirectMessages
+	case model.CHANNEL_GROUP:
+		return c.EnableGroupMessages
+	case model.CHANNEL_PRIVATE:
+		return c.EnablePrivateChannels
+	case model.CHANNEL_OPEN:
+		return c.EnablePublicChannels
+	default:
+		return false
+	}
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -79,5 +134,10 @@ func (p *Plugin) OnConfigurationChange() error {
 
 	p.setConfiguration(configuration)
 
+	// Badges are only created on activation if the integration was enabled.
+	if p.BotUserID != "" && configuration.EnableBadges && p.getBadgesMap() == nil {
+		p.EnsureBadges()
+	}
+
 	return nil
 }
@@ -7,4 +7,53 @@ const (
 	AchievementNameWinTen   = "Master"
 	AchievementNamePlayOnce = "Beginner"
 	AchievementNameStreak   = "Smart"
+	AchievementNameDaily    = "Challenger"
+	AchievementNameWeekly   = "Regular"
+	AchievementNameMonthly  = "Devoted"
+	DifficultyEasy          = "easy"
+	DifficultyMedium        = "medium"
+	DifficultyHard          = "hard"
+	KeyLeaderboard          = "leaderboard"
+	KeyPrefixLeaderboard    = "leaderboard_"
+	KeyPrefixStats          = "stats_"
+	KeyPrefixChannelGames   = "channel_games_"
+	KeyPrefixHistoryGame    = "history_game_"
+	KeyPrefixHistoryUser    = "history_user_"
+	MaxHistorySize          = 500
+	EventTypeFlip           = "flip"
+	EventTypeResign         = "resign"
+	EventTypeSkip           = "skip"
+	EventTypeOfferDraw      = "offer_draw"
+	EventTypeAcceptDraw     = "accept_draw"
+	KeyActiveGames          = "active_games"
+	KeyPrefixInvitation     = "invitation_"
+	KeyPendingInvitations   = "pending_invitations"
+	KeyPrefixSeries         = "series_"
+	KeyPrefixBotStats       = "bot_stats_"
+	KeyPrefixSoloBests      = "solo_bests_"
+	KeyPrefixSoloRanking    = "solo_leaderboard_"
+	KeyPrefixDailyChallenge = "daily_challenge_"
+	KeyPrefixDailyStreak    = "daily_streak_"
+	KeyPrefixRating         = "rating_"
+	KeyMatchmakingQueue     = "matchmaking_queue"
+	InitialRating           = 1500
+	ProvisionalGames        = 10
+	BotMemoryEasy           = "easy"
+	BotMemoryMedium         = "medium"
+	BotMemoryPerfect        = "perfect"
+	TimeoutActionRemind     = "remind"
+	TimeoutActionSkip       = "skip"
+	TimeoutActionForfeit    = "forfeit"
+	PostTypeResult          = "custom_memory_result"
+	BoardFormatMarkdown     = "markdown"
+	BoardFormatText         = "text"
+	LeaderboardPeriodAll    = "all"
+	LeaderboardPeriodMonth  = "month"
+	LeaderboardPeriodWeek   = "week"
+	GameResultWin           = "win"
+	GameResultLoss          = "loss"
+	GameResultDraw          = "draw"
+	CommandTrigger          = "memory"
+	MinPlayers              = 2
+	MaxPlayers              = 6
 )
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// dailyDateLayout is the format of the dates of the daily challenges.
+	dailyDateLayout = "2006-01-02"
+	// dailyDifficulty is the board size of the daily challenges.
+	dailyDifficulty = DifficultyMedium
+)
+
+var ErrDailyPlayed = errors.New("daily challenge already played")
+
+// errDailyPosted aborts posting the results of a daily challenge that were
+// already posted.
+var errDailyPosted = errors.New("daily challenge results already posted")
+
+func dailyChallengeKey(date string) string {
+	return KeyPrefixDailyChallenge + date
+}
+
+func dailyStreakKey(userID string) string {
+	return KeyPrefixDailyStreak + userID
+}
+
+// getDailyChallenge returns the challenge of date, or nil if nobody played
+// it.
+func (p *Plugin) getDailyChallenge(date string) (*DailyChallenge, error) {
+	var challenge *DailyChallenge
+	err := p.mm.KV.Get(dailyChallengeKey(date), &challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// updateDailyChallenge atomically applies update to the challenge of date,
+// drawing the seed of its board the first time it is played.
+func (p *Plugin) updateDailyChallenge(date string, update func(challenge *DailyChallenge) error) (*DailyChallenge, error) {
+	var challenge *DailyChallenge
+	err := p.mm.KV.SetAtomicWithRetries(dailyChallengeKey(date), func(oldValue []byte) (interface{}, error) {
+		challenge = &DailyChallenge{}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, challenge); err != nil {
+				return nil, err
+			}
+		} else {
+			seed, err := newSeed()
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot draw seed")
+			}
+			challenge.Date = date
+			challenge.Seed = seed
+			challenge.Difficulty = dailyDifficulty
+		}
+		if challenge.Games == nil {
+			challenge.Games = map[string]string{}
+		}
+		if challenge.Results == nil {
+			challenge.Results = SoloLeaderboard{}
+		}
+
+		if err := update(challenge); err != nil {
+			return nil, err
+		}
+
+		return challenge, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// getDailyStreak returns the daily challenge streak of userID.
+func (p *Plugin) getDailyStreak(userID string) (*DailyStreak, error) {
+	streak := &DailyStreak{}
+	err := p.mm.KV.Get(dailyStreakKey(userID), streak)
+	if err != nil {
+		return nil, err
+	}
+
+	return streak, nil
+}
+
+// recordDailyStreak atomically counts the challenge of date in the streak
+// of userID.
+func (p *Plugin) recordDailyStreak(userID, date string) (*DailyStreak, error) {
+	streak := &DailyStreak{}
+	err := p.mm.KV.SetAtomicWithRetries(dailyStreakKey(userID), func(oldValue []byte) (interface{}, error) {
+		streak = &DailyStreak{}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, streak); err != nil {
+				return nil, err
+			}
+		}
+
+		streak.Record(date)
+
+		return streak, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return streak, nil
+}
+
+// startDailyChallenge creates and begins the daily challenge of userID, in
+// their direct messages with the bot. Every user plays the same board, once
+// per day.
+func (p *Plugin) startDailyChallenge(userID string, now time.Time) (*Game, error) {
+	err := p.checkGameLimit(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	date := GetDailyDate(now)
+	challenge, err := p.updateDailyChallenge(date, func(challenge *DailyChallenge) error {
+		if _, ok := challenge.Games[userID]; ok {
+			return ErrDailyPlayed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.mm.Channel.GetDirect(userID, p.BotUserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get direct channel")
+	}
+
+	game, err := p.NewSeededGame([]string{userID}, c.Id, challenge.Difficulty, challenge.Seed)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create game")
+	}
+	game.Solo = true
+	game.Daily = date
+	game.start()
+
+	_, err = p.updateDailyChallenge(date, func(challenge *DailyChallenge) error {
+		if _, ok := challenge.Games[userID]; ok {
+			return ErrDailyPlayed
+		}
+		challenge.Games[userID] = game.GID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.addGame(game)
+	if err != nil {
+		_, _ = p.updateDailyChallenge(date, func(challenge *DailyChallenge) error {
+			delete(challenge.Games, userID)
+			return nil
+		})
+		return nil, err
+	}
+
+	return game, nil
+}
+
+// recordDailyResult ranks a cleared daily challenge game and extends the
+// streak of its player. It returns the challenge with the result, or nil if
+// the board was not cleared.
+func (p *Plugin) recordDailyResult(game *Game) (*DailyChallenge, *DailyStreak, error) {
+	result := game.SoloResult()
+	if result == nil {
+		return nil, nil, nil
+	}
+	userID := game.Players[0]
+
+	challenge, err := p.updateDailyChallenge(game.Daily, func(challenge *DailyChallenge) error {
+		challenge.Results[userID] = result
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot update daily challenge")
+	}
+
+	streak, err := p.recordDailyStreak(userID, game.Daily)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot update daily streak")
+	}
+
+	return challenge, streak, nil
+}
+
+// getDailyChallengeResponse returns a page of the ranking of the challenge of
+// date, along with the result and streak of userID.
+func (p *Plugin) getDailyChallengeResponse(date, userID string, page, perPage int) (*DailyChallengeResponse, error) {
+	challenge, err := p.getDailyChallenge(date)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		challenge = &DailyChallenge{Date: date, Difficulty: dailyDifficulty}
+	}
+
+	streak, err := p.getDailyStreak(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DailyChallengeResponse{
+		Date:       date,
+		Difficulty: challenge.Difficulty,
+		Total:      len(challenge.Results),
+		Entries:    p.getSoloLeaderboardEntries(challenge.Results, page, perPage),
+		GID:        challenge.Games[userID],
+		Result:     challenge.Results[userID],
+		Rank:       challenge.Results.RankOf(userID),
+		Streak:     streak.CurrentAt(GetDailyDate(time.Now())),
+		BestStreak: streak.Best,
+	}, nil
+}
+
+// postDailyResults posts the ranking of the challenge of the day before now
+// to the configured channel, once.
+func (p *Plugin) postDailyResults(now time.Time) {
+	channelID := p.getConfiguration().DailyChallengeChannelID
+	if channelID == "" {
+		return
+	}
+
+	date := previousDailyDate(GetDailyDate(now))
+	challenge, err := p.getDailyChallenge(date)
+	if err != nil {
+		p.mm.Log.Warn("Cannot get daily challenge", "date", date, "err", err.Error())
+		return
+	}
+	if challenge == nil || challenge.Posted {
+		return
+	}
+
+	challenge, err = p.updateDailyChallenge(date, func(challenge *DailyChallenge) error {
+		if challenge.Posted {
+			return errDailyPosted
+		}
+		challenge.Posted = true
+		return nil
+	})
+	if errors.Is(err, errDailyPosted) {
+		return
+	}
+	if err != nil {
+		p.mm.Log.Warn("Cannot claim daily challenge results", "date", date, "err", err.Error())
+		return
+	}
+
+	err = p.mm.Post.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: channelID,
+		Message:   p.formatDailyResults(challenge),
+	})
+	if err != nil {
+		p.mm.Log.Warn("Cannot post daily challenge results", "date", date, "err", err.Error())
+	}
+}
+
+// formatDailyResults describes the best results of challenge.
+func (p *Plugin) formatDailyResults(challenge *DailyChallenge) string {
+	text := fmt.Sprintf("#### Memory daily challenge of %s\n", challenge.Date)
+
+	entries := p.getSoloLeaderboardEntries(challenge.Results, 0, leaderboardSize)
+	if len(entries) == 0 {
+		text += fmt.Sprintf("Nobody cleared the board out of %d players.\n", len(challenge.Games))
+	} else {
+		text += fmt.Sprintf("%d of %d players cleared the board.\n\n", len(challenge.Results), len(challenge.Games))
+		text += "| # | Player | Moves | Time |\n|:-|:-|-:|-:|\n"
+		for _, entry := range entries {
+			text += fmt.Sprintf("| %d | @%s | %d | %s |\n", entry.Rank, entry.Username, entry.Moves, formatDuration(entry.Duration))
+		}
+	}
+
+	return text + "\nPlay today's challenge with `/memory daily`."
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyChallenge(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{DailyChallengeChannelID: "results"})
+	now := time.Date(2021, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+	game1, err := p.startDailyChallenge("user1", now)
+	require.NoError(t, err)
+	game2, err := p.startDailyChallenge("user2", now)
+	require.NoError(t, err)
+	assert.Equal(t, game1.CardValues, game2.CardValues)
+	assert.Equal(t, "2021-03-04", game1.Daily)
+	assert.Equal(t, "user1__bot", game1.ChannelID)
+
+	_, err = p.startDailyChallenge("user1", now)
+	assert.ErrorIs(t, err, ErrDailyPlayed)
+
+	pairs := map[string][]int{}
+	for i, value := range game1.CardValues {
+		pairs[value] = append(pairs[value], i)
+	}
+	seq := 0
+	for _, indexes := range pairs {
+		for _, index := range indexes {
+			require.Equal(t, http.StatusOK, flipCard(p, "user1", game1.GID, index, seq))
+			seq++
+		}
+	}
+
+	resp, err := p.getDailyChallengeResponse("2021-03-04", "user1", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Total)
+	assert.Equal(t, 1, resp.Rank)
+	require.NotNil(t, resp.Result)
+	assert.Equal(t, len(pairs), resp.Result.Moves)
+
+	streak, err := p.getDailyStreak("user1")
+	require.NoError(t, err)
+	assert.Equal(t, DailyStreak{Last: "2021-03-04", Current: 1, Best: 1}, *streak)
+
+	p.postDailyResults(now)
+	assert.Empty(t, p.API.(*fakeAPI).posts)
+
+	p.postDailyResults(now.Add(24 * time.Hour))
+	p.postDailyResults(now.Add(25 * time.Hour))
+	require.Len(t, p.API.(*fakeAPI).posts, 1)
+	assert.Equal(t, "results", p.API.(*fakeAPI).posts[0].ChannelId)
+	assert.Contains(t, p.API.(*fakeAPI).posts[0].Message, "| 1 | @user1 |")
+}
+
+func TestDailyStreak(t *testing.T) {
+	streak := &DailyStreak{}
+	streak.Record("2021-02-27")
+	streak.Record("2021-02-28")
+	streak.Record("2021-03-01")
+	streak.Record("2021-03-01")
+	assert.Equal(t, 3, streak.Current)
+	assert.Equal(t, 3, streak.CurrentAt("2021-03-02"))
+	assert.Equal(t, 0, streak.CurrentAt("2021-03-03"))
+
+	streak.Record("2021-03-03")
+	assert.Equal(t, 1, streak.Current)
+	assert.Equal(t, 3, streak.Best)
+}
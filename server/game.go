@@ -0,0 +1,587 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+var (
+	ErrNotYourTurn      = errors.New("not your turn")
+	ErrAlreadyFlipped   = errors.New("card already flipped")
+	ErrIndexOutOfRange  = errors.New("card index out of range")
+	ErrStaleMove        = errors.New("move based on a stale game state")
+	ErrNotAPlayer       = errors.New("not a player of this game")
+	ErrGameOver         = errors.New("game is over")
+	ErrGameStarted      = errors.New("game already started")
+	ErrGameNotStarted   = errors.New("game not started")
+	ErrAlreadyJoined    = errors.New("already joined")
+	ErrGameFull         = errors.New("game is full")
+	ErrNotOwner         = errors.New("not the owner of this game")
+	ErrNotEnoughPlayers = errors.New("not enough players")
+	ErrAmbiguousGame    = errors.New("several games match")
+	ErrDrawOffered      = errors.New("draw already offered")
+	ErrNoDrawOffer      = errors.New("no draw offer to accept")
+)
+
+// migrate upgrades games stored by older versions of the plugin. It returns
+// true for games stored before boards had a size, which have to be stored
+// again.
+func (g *Game) migrate() bool {
+	upgraded := false
+	if g.Rows == 0 && g.Columns == 0 {
+		// Games used to be played on the easy board only.
+		easy, _ := GetBoardSize(DifficultyEasy)
+		if len(g.CardValues) == easy.Cards() {
+			g.Rows, g.Columns = easy.Rows, easy.Columns
+			if g.ActiveAt == 0 && g.CreateAt == 0 {
+				g.ActiveAt = model.GetMillis()
+			}
+			upgraded = true
+		}
+	}
+	if g.ChannelID == "" {
+		// Games used to be stored under the ID of their channel.
+		g.ChannelID = g.GID
+	}
+	if len(g.Players) == 0 && g.LegacyCurrentPlayer != "" {
+		g.Players = []string{g.LegacyCurrentPlayer, g.LegacyOtherPlayer}
+		g.Owner = g.LegacyCurrentPlayer
+		g.Turn = 0
+		g.Started = true
+	}
+	g.LegacyCurrentPlayer = ""
+	g.LegacyOtherPlayer = ""
+	if g.ActiveAt == 0 {
+		g.ActiveAt = g.CreateAt
+	}
+
+	return upgraded
+}
+
+// touch records that a player acted on the game.
+func (g *Game) touch() {
+	g.ActiveAt = model.GetMillis()
+	g.Reminded = false
+}
+
+// CurrentPlayer returns the player that has to move.
+func (g *Game) CurrentPlayer() string {
+	if len(g.Players) == 0 {
+		return ""
+	}
+
+	return g.Players[g.Turn%len(g.Players)]
+}
+
+// IsPlayer returns whether userID is taking part in the game.
+func (g *Game) IsPlayer(userID string) bool {
+	return g.playerIndex(userID) != -1
+}
+
+func (g *Game) playerIndex(userID string) int {
+	for i, player := range g.Players {
+		if player == userID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Join adds userID to a game that has not started yet.
+func (g *Game) Join(userID string) error {
+	if g.Started {
+		return ErrGameStarted
+	}
+
+	if g.IsPlayer(userID) {
+		return ErrAlreadyJoined
+	}
+
+	if len(g.Players) >= MaxPlayers {
+		return ErrGameFull
+	}
+
+	g.Players = append(g.Players, userID)
+	g.Scores[userID] = 0
+	g.touch()
+
+	return nil
+}
+
+// Leave removes userID from a game that has not started yet. If the owner
+// leaves, the next player to join becomes the owner.
+func (g *Game) Leave(userID string) error {
+	if g.Started {
+		return ErrGameStarted
+	}
+
+	i := g.playerIndex(userID)
+	if i == -1 {
+		return ErrNotAPlayer
+	}
+
+	g.Players = append(g.Players[:i], g.Players[i+1:]...)
+	delete(g.Scores, userID)
+
+	if g.Owner == userID && len(g.Players) > 0 {
+		g.Owner = g.Players[0]
+	}
+	g.touch()
+
+	return nil
+}
+
+// Begin closes the game to new players and shuffles the turn order.
+func (g *Game) Begin(userID string) error {
+	if g.Started {
+		return ErrGameStarted
+	}
+
+	if g.Owner != userID {
+		return ErrNotOwner
+	}
+
+	if len(g.Players) < MinPlayers {
+		return ErrNotEnoughPlayers
+	}
+
+	rng := rand.New(rand.NewSource(g.Seed))
+	rng.Shuffle(len(g.Players), func(i, j int) { g.Players[i], g.Players[j] = g.Players[j], g.Players[i] })
+	g.start()
+
+	return nil
+}
+
+// start begins the game with the players in their current order.
+func (g *Game) start() {
+	g.StartOrder = append([]string{}, g.Players...)
+	g.Turn = 0
+	g.Started = true
+	g.touch()
+}
+
+// SeedRevealed returns whether the seed of the game can be revealed at time
+// now. Seeds are revealed once the game is over, and the seed shared by the
+// games of a daily challenge once the challenge is over.
+func (g *Game) SeedRevealed(now time.Time) bool {
+	if !g.Over {
+		return false
+	}
+
+	return g.Daily == "" || g.Daily < GetDailyDate(now)
+}
+
+// RematchOrder returns the turn order of a rematch of the game, in which the
+// player that moved second in this game moves first.
+func (g *Game) RematchOrder() []string {
+	order := g.StartOrder
+	if len(order) == 0 {
+		order = g.Participants()
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	return append(append([]string{}, order[1:]...), order[0])
+}
+
+// Flip flips the card at index on behalf of userID and returns its value.
+// Flipping the second card of a turn either scores a pair or hides both cards
+// again and passes the turn to the next player.
+func (g *Game) Flip(userID string, index int) (string, error) {
+	if !g.Started {
+		return "", ErrGameNotStarted
+	}
+
+	if g.Over {
+		return "", ErrGameOver
+	}
+
+	if index < 0 || index >= len(g.CardFlipped) {
+		return "", ErrIndexOutOfRange
+	}
+
+	if g.CardFlipped[index] {
+		return "", ErrAlreadyFlipped
+	}
+
+	if g.CurrentPlayer() != userID {
+		return "", ErrNotYourTurn
+	}
+
+	g.CardFlipped[index] = true
+	g.DrawOffers = nil
+	g.touch()
+	value := g.CardValues[index]
+	g.Events = append(g.Events, GameEvent{
+		Type:   EventTypeFlip,
+		UserID: userID,
+		Index:  index,
+		Value:  value,
+		At:     model.GetMillis(),
+	})
+
+	if g.LastFlipped == -1 {
+		g.LastFlipped = index
+		return value, nil
+	}
+
+	lastFlippedValue := g.CardValues[g.LastFlipped]
+	if lastFlippedValue == value {
+		g.Scores[userID]++
+		g.Streak++
+		if g.PassTurnOnMatch && !g.Finished() {
+			g.Turn = (g.Turn + 1) % len(g.Players)
+			g.Streak = 0
+		}
+	} else {
+		g.CardFlipped[g.LastFlipped] = false
+		g.CardFlipped[index] = false
+		g.Turn = (g.Turn + 1) % len(g.Players)
+		g.Streak = 0
+	}
+	g.LastFlipped = -1
+
+	if g.Finished() {
+		g.end()
+	}
+
+	return value, nil
+}
+
+// Resign removes userID from the turn order of a started game. The game is
+// over once a single player is left.
+func (g *Game) Resign(userID string) error {
+	if !g.Started {
+		return ErrGameNotStarted
+	}
+
+	if g.Over {
+		return ErrGameOver
+	}
+
+	i := g.playerIndex(userID)
+	if i == -1 {
+		return ErrNotAPlayer
+	}
+
+	if i == g.Turn {
+		if g.LastFlipped != -1 {
+			g.CardFlipped[g.LastFlipped] = false
+			g.LastFlipped = -1
+		}
+		g.Streak = 0
+	}
+
+	g.Players = append(g.Players[:i], g.Players[i+1:]...)
+	g.Resignations = append(g.Resignations, userID)
+	g.DrawOffers = removeString(g.DrawOffers, userID)
+	g.touch()
+	g.Events = append(g.Events, GameEvent{
+		Type:   EventTypeResign,
+		UserID: userID,
+		Index:  -1,
+		At:     model.GetMillis(),
+	})
+	if i < g.Turn {
+		g.Turn--
+	}
+	if g.Turn >= len(g.Players) {
+		g.Turn = 0
+	}
+
+	if len(g.Players) <= 1 {
+		g.end()
+	} else if len(g.DrawOffers) == len(g.Players) {
+		g.Drawn = true
+		g.end()
+	}
+
+	return nil
+}
+
+// OfferDraw makes userID propose to end the game in a draw. The offer stands
+// until a card is flipped.
+func (g *Game) OfferDraw(userID string) error {
+	if !g.Started {
+		return ErrGameNotStarted
+	}
+
+	if g.Over {
+		return ErrGameOver
+	}
+
+	if !g.IsPlayer(userID) {
+		return ErrNotAPlayer
+	}
+
+	if g.Solo {
+		return ErrNotEnoughPlayers
+	}
+
+	if len(g.DrawOffers) != 0 {
+		return ErrDrawOffered
+	}
+
+	g.DrawOffers = []string{userID}
+	g.Events = append(g.Events, GameEvent{
+		Type:   EventTypeOfferDraw,
+		UserID: userID,
+		Index:  -1,
+		At:     model.GetMillis(),
+	})
+	g.touch()
+
+	return nil
+}
+
+// AcceptDraw makes userID agree to the standing draw offer. The game ends in
+// a draw once every remaining player agreed.
+func (g *Game) AcceptDraw(userID string) error {
+	if !g.Started {
+		return ErrGameNotStarted
+	}
+
+	if g.Over {
+		return ErrGameOver
+	}
+
+	if !g.IsPlayer(userID) {
+		return ErrNotAPlayer
+	}
+
+	if len(g.DrawOffers) == 0 {
+		return ErrNoDrawOffer
+	}
+
+	for _, player := range g.DrawOffers {
+		if player == userID {
+			return ErrDrawOffered
+		}
+	}
+
+	g.DrawOffers = append(g.DrawOffers, userID)
+	g.Events = append(g.Events, GameEvent{
+		Type:   EventTypeAcceptDraw,
+		UserID: userID,
+		Index:  -1,
+		At:     model.GetMillis(),
+	})
+	g.touch()
+
+	if len(g.DrawOffers) == len(g.Players) {
+		g.Drawn = true
+		g.end()
+	}
+
+	return nil
+}
+
+// SkipTurn passes the turn of the current player to the next one, hiding the
+// card they may have flipped.
+func (g *Game) SkipTurn() error {
+	if !g.Started {
+		return ErrGameNotStarted
+	}
+
+	if g.Over {
+		return ErrGameOver
+	}
+
+	if g.LastFlipped != -1 {
+		g.CardFlipped[g.LastFlipped] = false
+		g.LastFlipped = -1
+	}
+	g.Events = append(g.Events, GameEvent{
+		Type:   EventTypeSkip,
+		UserID: g.CurrentPlayer(),
+		Index:  -1,
+		At:     model.GetMillis(),
+	})
+	g.Turn = (g.Turn + 1) % len(g.Players)
+	g.Streak = 0
+	g.touch()
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	filtered := []string{}
+	for _, v := range values {
+		if v != value {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+func (g *Game) end() {
+	g.Over = true
+	g.EndAt = model.GetMillis()
+}
+
+// SoloResult returns the result of a solo game that was cleared, or nil if it
+// was given up.
+func (g *Game) SoloResult() *SoloResult {
+	if !g.Solo || !g.Finished() || len(g.Players) != 1 {
+		return nil
+	}
+
+	moves, _ := g.PlayerMoves()
+	return &SoloResult{
+		GID:      g.GID,
+		Moves:    moves[g.Players[0]],
+		Duration: g.EndAt - g.CreateAt,
+		EndAt:    g.EndAt,
+	}
+}
+
+// Participants returns every player that took part in the game, including
+// the ones that resigned.
+func (g *Game) Participants() []string {
+	participants := append([]string{}, g.Players...)
+	return append(participants, g.Resignations...)
+}
+
+// Winner returns the remaining player with the highest score, or an empty
+// string if several remaining players share the highest score.
+func (g *Game) Winner() string {
+	if g.Drawn {
+		return ""
+	}
+
+	winner := ""
+	best := -1
+	for _, player := range g.Players {
+		score := g.Scores[player]
+		if score > best {
+			winner = player
+			best = score
+		} else if score == best {
+			winner = ""
+		}
+	}
+
+	return winner
+}
+
+// Result returns whether userID won, lost or drew the game. Players that
+// resigned lost it.
+func (g *Game) Result(userID string) string {
+	winner := g.Winner()
+	if winner == userID {
+		return GameResultWin
+	}
+	if winner != "" || !g.IsPlayer(userID) {
+		return GameResultLoss
+	}
+	if g.Drawn {
+		return GameResultDraw
+	}
+
+	for _, player := range g.Players {
+		if g.Scores[player] > g.Scores[userID] {
+			return GameResultLoss
+		}
+	}
+
+	return GameResultDraw
+}
+
+// HeadToHead returns whether userID won, lost or drew against opponentID.
+// Players that did not resign beat the ones that did, and otherwise the
+// highest score wins.
+func (g *Game) HeadToHead(userID, opponentID string) string {
+	if g.IsPlayer(userID) != g.IsPlayer(opponentID) {
+		if g.IsPlayer(userID) {
+			return GameResultWin
+		}
+		return GameResultLoss
+	}
+
+	switch {
+	case g.Drawn && g.IsPlayer(userID):
+		return GameResultDraw
+	case g.Scores[userID] > g.Scores[opponentID]:
+		return GameResultWin
+	case g.Scores[userID] < g.Scores[opponentID]:
+		return GameResultLoss
+	default:
+		return GameResultDraw
+	}
+}
+
+// PlayerMoves replays the events of the game and returns, for each player,
+// the number of pairs they turned over and their longest run of matched pairs.
+func (g *Game) PlayerMoves() (moves, matchStreaks map[string]int) {
+	moves = map[string]int{}
+	matchStreaks = map[string]int{}
+	streaks := map[string]int{}
+
+	var pending *GameEvent
+	for i := range g.Events {
+		event := &g.Events[i]
+		switch event.Type {
+		case EventTypeResign, EventTypeSkip:
+			pending = nil
+			continue
+		case EventTypeFlip:
+		default:
+			continue
+		}
+
+		if pending == nil || pending.UserID != event.UserID {
+			pending = event
+			continue
+		}
+
+		moves[event.UserID]++
+		if pending.Value == event.Value {
+			streaks[event.UserID]++
+			if streaks[event.UserID] > matchStreaks[event.UserID] {
+				matchStreaks[event.UserID] = streaks[event.UserID]
+			}
+		} else {
+			streaks[event.UserID] = 0
+		}
+		pending = nil
+	}
+
+	return moves, matchStreaks
+}
+
+// Finished returns whether every card on the board has been matched.
+func (g *Game) Finished() bool {
+	for _, flipped := range g.CardFlipped {
+		if !flipped {
+			return false
+		}
+	}
+
+	return true
+}
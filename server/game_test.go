@@ -0,0 +1,206 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLobbyGame(t *testing.T, players ...string) *Game {
+	t.Helper()
+
+	game, err := (&Plugin{}).NewGame(players, "game", DifficultyEasy)
+	require.NoError(t, err)
+
+	return game
+}
+
+func TestGameLobby(t *testing.T) {
+	game := newLobbyGame(t, "owner")
+
+	assert.ErrorIs(t, game.Begin("owner"), ErrNotEnoughPlayers)
+	assert.ErrorIs(t, game.Join("owner"), ErrAlreadyJoined)
+
+	for _, player := range []string{"user1", "user2", "user3", "user4", "user5"} {
+		require.NoError(t, game.Join(player))
+	}
+	assert.ErrorIs(t, game.Join("user6"), ErrGameFull)
+
+	require.NoError(t, game.Leave("user5"))
+	assert.ErrorIs(t, game.Leave("user5"), ErrNotAPlayer)
+	assert.NotContains(t, game.Scores, "user5")
+
+	assert.ErrorIs(t, game.Begin("user1"), ErrNotOwner)
+	require.NoError(t, game.Leave("owner"))
+	assert.Equal(t, "user1", game.Owner)
+
+	require.NoError(t, game.Begin("user1"))
+	assert.True(t, game.Started)
+	assert.ElementsMatch(t, []string{"user1", "user2", "user3", "user4"}, game.Players)
+	assert.ErrorIs(t, game.Join("user5"), ErrGameStarted)
+	assert.ErrorIs(t, game.Leave("user1"), ErrGameStarted)
+}
+
+func TestGameTurnOrder(t *testing.T) {
+	game := &Game{
+		CardValues:  []string{"joker", "heartsAce", "joker", "heartsAce"},
+		CardFlipped: []bool{false, false, false, false},
+		LastFlipped: -1,
+		Players:     []string{"user1", "user2", "user3"},
+		Started:     true,
+		Scores:      map[string]int{"user1": 0, "user2": 0, "user3": 0},
+	}
+
+	_, err := game.Flip("user1", 0)
+	require.NoError(t, err)
+	_, err = game.Flip("user1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "user2", game.CurrentPlayer())
+
+	_, err = game.Flip("user1", 0)
+	assert.ErrorIs(t, err, ErrNotYourTurn)
+
+	_, err = game.Flip("user2", 0)
+	require.NoError(t, err)
+	_, err = game.Flip("user2", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "user2", game.CurrentPlayer())
+	assert.Equal(t, 1, game.Scores["user2"])
+
+	_, err = game.Flip("user2", 1)
+	require.NoError(t, err)
+	require.NoError(t, game.Resign("user2"))
+	assert.False(t, game.CardFlipped[1])
+	assert.Equal(t, -1, game.LastFlipped)
+	assert.Equal(t, "user3", game.CurrentPlayer())
+	assert.False(t, game.Over)
+
+	require.NoError(t, game.Resign("user1"))
+	assert.True(t, game.Over)
+	assert.Equal(t, "user3", game.Winner())
+	assert.Equal(t, []string{"user2", "user1"}, game.Resignations)
+
+	_, err = game.Flip("user3", 1)
+	assert.ErrorIs(t, err, ErrGameOver)
+}
+
+func TestGameMigrate(t *testing.T) {
+	game, err := decodeGame([]byte(`{"GID":"game","CurrentPlayer":"user1","OtherPlayer":"user2","Scores":{"user1":1,"user2":2}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"user1", "user2"}, game.Players)
+	assert.Equal(t, "user1", game.CurrentPlayer())
+	assert.True(t, game.Started)
+	assert.Empty(t, game.LegacyCurrentPlayer)
+}
+
+func TestGameResults(t *testing.T) {
+	game := &Game{
+		CardValues:  []string{"joker", "joker", "heartsAce", "heartsAce", "clubsAce", "clubsAce"},
+		CardFlipped: make([]bool, 6),
+		LastFlipped: -1,
+		Players:     []string{"user1", "user2", "user3"},
+		Started:     true,
+		Scores:      map[string]int{"user1": 0, "user2": 0, "user3": 0},
+	}
+
+	for _, index := range []int{0, 1, 2, 4} {
+		_, err := game.Flip("user1", index)
+		require.NoError(t, err)
+	}
+	for _, index := range []int{2, 3} {
+		_, err := game.Flip("user2", index)
+		require.NoError(t, err)
+	}
+	require.NoError(t, game.Resign("user3"))
+
+	assert.Empty(t, game.Winner())
+	assert.Equal(t, GameResultDraw, game.Result("user1"))
+	assert.Equal(t, GameResultDraw, game.Result("user2"))
+	assert.Equal(t, GameResultLoss, game.Result("user3"))
+	assert.Equal(t, GameResultDraw, game.HeadToHead("user1", "user2"))
+	assert.Equal(t, GameResultWin, game.HeadToHead("user2", "user3"))
+	assert.Equal(t, GameResultLoss, game.HeadToHead("user3", "user1"))
+
+	moves, matchStreaks := game.PlayerMoves()
+	assert.Equal(t, map[string]int{"user1": 2, "user2": 1}, moves)
+	assert.Equal(t, map[string]int{"user1": 1, "user2": 1}, matchStreaks)
+
+	_, err := game.Flip("user2", 4)
+	require.NoError(t, err)
+	_, err = game.Flip("user2", 5)
+	require.NoError(t, err)
+	assert.True(t, game.Over)
+	assert.Equal(t, "user2", game.Winner())
+
+	stats := &PlayerStats{}
+	stats.RecordGame(game, "user2")
+	assert.Equal(t, 1, stats.Wins)
+	assert.Equal(t, 2, stats.PairsMatched)
+	assert.Equal(t, 2, stats.Moves)
+	assert.Equal(t, 2, stats.LongestMatchStreak)
+	assert.Equal(t, 1, stats.Opponents["user1"].Wins)
+	assert.Equal(t, 1, stats.Opponents["user3"].Wins)
+}
+
+func TestGamePassTurnOnMatch(t *testing.T) {
+	game := &Game{
+		CardValues:      []string{"joker", "joker", "heartsAce", "heartsAce"},
+		CardFlipped:     make([]bool, 4),
+		LastFlipped:     -1,
+		Players:         []string{"user1", "user2"},
+		Started:         true,
+		Scores:          map[string]int{"user1": 0, "user2": 0},
+		PassTurnOnMatch: true,
+	}
+
+	_, err := game.Flip("user1", 0)
+	require.NoError(t, err)
+	_, err = game.Flip("user1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, game.Scores["user1"])
+	assert.Equal(t, "user2", game.CurrentPlayer())
+
+	_, err = game.Flip("user2", 2)
+	require.NoError(t, err)
+	_, err = game.Flip("user2", 3)
+	require.NoError(t, err)
+	assert.True(t, game.Over)
+	assert.Empty(t, game.Winner())
+}
+
+func TestGameDraw(t *testing.T) {
+	game := &Game{
+		CardValues:  []string{"joker", "joker", "heartsAce", "heartsAce"},
+		CardFlipped: make([]bool, 4),
+		LastFlipped: -1,
+		Players:     []string{"user1", "user2", "user3"},
+		Started:     true,
+		Scores:      map[string]int{"user1": 0, "user2": 0, "user3": 0},
+	}
+
+	assert.ErrorIs(t, game.AcceptDraw("user2"), ErrNoDrawOffer)
+	require.NoError(t, game.OfferDraw("user2"))
+	assert.ErrorIs(t, game.OfferDraw("user1"), ErrDrawOffered)
+	assert.ErrorIs(t, game.AcceptDraw("user2"), ErrDrawOffered)
+
+	_, err := game.Flip("user1", 0)
+	require.NoError(t, err)
+	assert.Empty(t, game.DrawOffers)
+	_, err = game.Flip("user1", 1)
+	require.NoError(t, err)
+
+	require.NoError(t, game.OfferDraw("user2"))
+	require.NoError(t, game.AcceptDraw("user1"))
+	assert.False(t, game.Over)
+	require.NoError(t, game.Resign("user3"))
+	assert.True(t, game.Over)
+	assert.True(t, game.Drawn)
+	assert.Empty(t, game.Winner())
+	assert.Equal(t, GameResultDraw, game.Result("user1"))
+	assert.Equal(t, GameResultDraw, game.Result("user2"))
+	assert.Equal(t, GameResultLoss, game.Result("user3"))
+	assert.Equal(t, GameResultDraw, game.HeadToHead("user1", "user2"))
+	assert.Equal(t, GameResultWin, game.HeadToHead("user2", "user3"))
+}
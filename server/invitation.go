@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// invitationExpiry is how long an invitation can be answered.
+const invitationExpiry = time.Hour
+
+var (
+	ErrInvitationNotFound = errors.New("invitation not found or expired")
+	ErrNotInvited         = errors.New("not the invited user")
+)
+
+// Invitation is a pending request to play a game in a direct message. The
+// game is only created once the invitee accepts it.
+type Invitation struct {
+	ID         string `json:"id"`
+	ChannelID  string `json:"channelID"`
+	Inviter    string `json:"inviter"`
+	Invitee    string `json:"invitee"`
+	Difficulty string `json:"difficulty"`
+	PostID     string `json:"postID"`
+	CreateAt   int64  `json:"createAt"`
+	ExpireAt   int64  `json:"expireAt"`
+}
+
+// Expired reports whether the invitation can no longer be answered at time
+// now, in milliseconds.
+func (inv *Invitation) Expired(now int64) bool {
+	return now >= inv.ExpireAt
+}
+
+func invitationKey(id string) string {
+	return KeyPrefixInvitation + id
+}
+
+// inviteToGame invites the other member of the direct message c to a game
+// with a bot post the invitee can accept or decline.
+func (p *Plugin) inviteToGame(actingUserID string, c *model.Channel, difficulty string) (*Invitation, error) {
+	config := p.getConfiguration()
+	if !config.IsChannelTypeEnabled(c.Type) {
+		return nil, ErrChannelTypeDisabled
+	}
+
+	if difficulty == "" {
+		difficulty = config.DefaultDifficulty
+	}
+
+	err := p.checkGameLimit(actingUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := model.GetMillis()
+	inv := &Invitation{
+		ID:         model.NewId(),
+		ChannelID:  c.Id,
+		Inviter:    actingUserID,
+		Invitee:    c.GetOtherUserIdForDM(actingUserID),
+		Difficulty: difficulty,
+		CreateAt:   now,
+		ExpireAt:   now + invitationExpiry.Milliseconds(),
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: c.Id,
+		Message: fmt.Sprintf("@%s invited @%s to play memory. The invitation expires in %d minutes.",
+			p.getUsername(inv.Inviter), p.getUsername(inv.Invitee), int(invitationExpiry/time.Minute)),
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{
+			invitationAction(inv.ID, "accept", "Accept", "primary"),
+			invitationAction(inv.ID, "decline", "Decline", "danger"),
+		},
+	}})
+
+	err = p.mm.Post.CreatePost(post)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot post invitation")
+	}
+	inv.PostID = post.Id
+
+	err = p.storeInvitation(inv, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return inv, nil
+}
+
+// storeInvitation keeps inv until it expires and adds it to the pending
+// invitations, at time now in milliseconds.
+func (p *Plugin) storeInvitation(inv *Invitation, now int64) error {
+	expiry := time.Duration(inv.ExpireAt-now) * time.Millisecond
+	if expiry <= 0 {
+		return ErrInvitationNotFound
+	}
+
+	_, err := p.mm.KV.Set(invitationKey(inv.ID), inv, pluginapi.SetExpiry(expiry))
+	if err != nil {
+		return errors.Wrap(err, "cannot set invitation")
+	}
+
+	err = p.updatePendingInvitations(func(invitations []*Invitation) []*Invitation {
+		return append(invitations, inv)
+	})
+	if err != nil {
+		_ = p.mm.KV.Delete(invitationKey(inv.ID))
+		return errors.Wrap(err, "cannot add pending invitation")
+	}
+
+	return nil
+}
+
+func invitationAction(id, action, name, style string) *model.PostAction {
+	return &model.PostAction{
+		Id:    action,
+		Type:  model.POST_ACTION_TYPE_BUTTON,
+		Name:  name,
+		Style: style,
+		Integration: &model.PostActionIntegration{
+			URL: fmt.Sprintf("/plugins/%s/api/v1/invitation/%s/%s", manifest.Id, id, action),
+		},
+	}
+}
+
+// updatePendingInvitations atomically applies update to the index of the
+// invitations that were not answered yet. Expired invitations are kept in the
+// index until their post is updated.
+func (p *Plugin) updatePendingInvitations(update func(invitations []*Invitation) []*Invitation) error {
+	return p.mm.KV.SetAtomicWithRetries(KeyPendingInvitations, func(oldValue []byte) (interface{}, error) {
+		invitations := []*Invitation{}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, &invitations); err != nil {
+				return nil, err
+			}
+		}
+
+		invitations = update(invitations)
+		if len(invitations) == 0 {
+			return nil, nil
+		}
+
+		return invitations, nil
+	})
+}
+
+func (p *Plugin) removePendingInvitation(id string) error {
+	return p.updatePendingInvitations(func(invitations []*Invitation) []*Invitation {
+		kept := []*Invitation{}
+		for _, inv := range invitations {
+			if inv.ID != id {
+				kept = append(kept, inv)
+			}
+		}
+		return kept
+	})
+}
+
+// takeInvitation atomically removes the invitation id so that userID can
+// answer it. Only the invitee can answer an invitation, and only once.
+func (p *Plugin) takeInvitation(id, userID string) (*Invitation, error) {
+	var inv *Invitation
+	err := p.mm.KV.SetAtomicWithRetries(invitationKey(id), func(oldValue []byte) (interface{}, error) {
+		if len(oldValue) == 0 {
+			return nil, ErrInvitationNotFound
+		}
+
+		inv = &Invitation{}
+		if err := json.Unmarshal(oldValue, inv); err != nil {
+			return nil, err
+		}
+		if inv.Invitee != userID {
+			return nil, ErrNotInvited
+		}
+		if inv.Expired(model.GetMillis()) {
+			return nil, ErrInvitationNotFound
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.removePendingInvitation(id)
+	if err != nil {
+		p.mm.Log.Warn("Cannot remove pending invitation", "invitationID", id, "err", err.Error())
+	}
+
+	return inv, nil
+}
+
+// acceptInvitation starts the game userID was invited to and tells both
+// players about it.
+func (p *Plugin) acceptInvitation(id, userID string) (*Invitation, *Game, error) {
+	inv, err := p.takeInvitation(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	game, err := p.startInvitedGame(inv)
+	if err != nil {
+		// Put the invitation back, so that it can be accepted once the game
+		// can start.
+		if restoreErr := p.storeInvitation(inv, model.GetMillis()); restoreErr != nil {
+			p.mm.Log.Warn("Cannot restore invitation", "invitationID", inv.ID, "err", restoreErr.Error())
+		}
+		p.notify(inv.Inviter, strings.TrimSpace(fmt.Sprintf("@%s accepted your invitation to play memory, but the game could not start. %s",
+			p.getUsername(inv.Invitee), gameErrorMessage(err, ""))))
+		return inv, nil, err
+	}
+
+	for _, player := range []string{inv.Inviter, inv.Invitee} {
+		p.mm.Frontend.PublishWebSocketEvent("invitation_accepted", map[string]interface{}{
+			"gID":       game.GID,
+			"channelID": game.ChannelID,
+		}, &model.WebsocketBroadcast{UserId: player})
+	}
+	p.notify(inv.Inviter, fmt.Sprintf("@%s accepted your invitation to play memory.", p.getUsername(inv.Invitee)))
+
+	return inv, game, nil
+}
+
+// startInvitedGame starts the game of the accepted invitation inv.
+func (p *Plugin) startInvitedGame(inv *Invitation) (*Game, error) {
+	c, err := p.mm.Channel.Get(inv.ChannelID)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get channel")
+	}
+
+	return p.startGame(inv.Inviter, c, inv.Difficulty)
+}
+
+// declineInvitation drops the invitation userID was sent and tells the
+// inviter about it.
+func (p *Plugin) declineInvitation(id, userID string) (*Invitation, error) {
+	inv, err := p.takeInvitation(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mm.Frontend.PublishWebSocketEvent("invitation_declined", map[string]interface{}{
+		"invitationID": inv.ID,
+		"channelID":    inv.ChannelID,
+	}, &model.WebsocketBroadcast{UserId: inv.Inviter})
+	p.notify(inv.Inviter, fmt.Sprintf("@%s declined your invitation to play memory.", p.getUsername(inv.Invitee)))
+
+	return inv, nil
+}
+
+// expireInvitations removes the buttons of the invitations that expired at
+// time now, in milliseconds, and tells the inviters.
+func (p *Plugin) expireInvitations(now int64) {
+	var expired []*Invitation
+	err := p.updatePendingInvitations(func(invitations []*Invitation) []*Invitation {
+		expired = nil
+		kept := []*Invitation{}
+		for _, inv := range invitations {
+			if inv.Expired(now) {
+				expired = append(expired, inv)
+			} else {
+				kept = append(kept, inv)
+			}
+		}
+		return kept
+	})
+	if err != nil {
+		p.mm.Log.Warn("Cannot expire invitations", "err", err.Error())
+		return
+	}
+
+	for _, inv := range expired {
+		// The KV store drops expired invitations, but only lazily.
+		_ = p.mm.KV.Delete(invitationKey(inv.ID))
+
+		err = p.mm.Post.UpdatePost(&model.Post{
+			Id:        inv.PostID,
+			UserId:    p.BotUserID,
+			ChannelId: inv.ChannelID,
+			Message:   fmt.Sprintf("The invitation from @%s to play memory expired.", p.getUsername(inv.Inviter)),
+		})
+		if err != nil {
+			p.mm.Log.Warn("Cannot update invitation post", "invitationID", inv.ID, "err", err.Error())
+		}
+
+		p.notify(inv.Inviter, fmt.Sprintf("@%s did not answer your invitation to play memory in time.", p.getUsername(inv.Invitee)))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func answerInvitation(p *Plugin, userID, invitationID, action string) *model.PostActionIntegrationResponse {
+	req := &model.PostActionIntegrationRequest{UserId: userID}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/invitation/"+invitationID+"/"+action, bytes.NewReader(req.ToJson()))
+	r.Header.Set("Mattermost-User-ID", userID)
+
+	p.router.ServeHTTP(w, r)
+
+	return model.PostActionIntegrationResponseFromJson(w.Result().Body)
+}
+
+func TestInvitation(t *testing.T) {
+	dm := &model.Channel{Id: "user1__user2", Type: model.CHANNEL_DIRECT, Name: "user1__user2"}
+
+	t.Run("accept", func(t *testing.T) {
+		p := newTestPlugin(t)
+		p.setConfiguration(&configuration{EnableDirectMessages: true})
+
+		inv, err := p.inviteToGame("user1", dm, DifficultyEasy)
+		require.NoError(t, err)
+		assert.Equal(t, "user2", inv.Invitee)
+		require.Len(t, p.API.(*fakeAPI).posts, 1)
+		assert.Len(t, p.API.(*fakeAPI).posts[0].Attachments()[0].Actions, 2)
+
+		resp := answerInvitation(p, "user1", inv.ID, "accept")
+		assert.Equal(t, "This invitation is not for you.", resp.EphemeralText)
+
+		resp = answerInvitation(p, "user2", inv.ID, "accept")
+		require.NotNil(t, resp.Update)
+		games, err := p.getChannelGames(dm.Id)
+		require.NoError(t, err)
+		require.Len(t, games, 1)
+		assert.True(t, games[0].Started)
+		assert.ElementsMatch(t, []string{"user1", "user2"}, games[0].Players)
+
+		resp = answerInvitation(p, "user2", inv.ID, "decline")
+		assert.Equal(t, "This invitation expired or was already answered.", resp.EphemeralText)
+	})
+
+	t.Run("accept failure", func(t *testing.T) {
+		p := newTestPlugin(t)
+		p.setConfiguration(&configuration{EnableDirectMessages: true, EnableNotifications: true})
+		api := p.API.(*fakeAPI)
+
+		inv, err := p.inviteToGame("user1", dm, DifficultyEasy)
+		require.NoError(t, err)
+
+		p.setConfiguration(&configuration{EnableNotifications: true})
+		resp := answerInvitation(p, "user2", inv.ID, "accept")
+		assert.Equal(t, "Games are disabled in this type of channel.", resp.EphemeralText)
+		assert.Nil(t, resp.Update)
+		require.Len(t, api.posts, 2)
+		assert.Equal(t, "bot__user1", api.posts[1].ChannelId)
+		assert.Contains(t, api.posts[1].Message, "the game could not start")
+
+		pending := []*Invitation{}
+		require.NoError(t, p.mm.KV.Get(KeyPendingInvitations, &pending))
+		require.Len(t, pending, 1)
+		assert.Equal(t, inv.ID, pending[0].ID)
+
+		p.setConfiguration(&configuration{EnableDirectMessages: true, EnableNotifications: true})
+		resp = answerInvitation(p, "user2", inv.ID, "accept")
+		require.NotNil(t, resp.Update)
+		games, err := p.getChannelGames(dm.Id)
+		require.NoError(t, err)
+		assert.Len(t, games, 1)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		p := newTestPlugin(t)
+		p.setConfiguration(&configuration{EnableDirectMessages: true})
+
+		inv, err := p.inviteToGame("user1", dm, DifficultyEasy)
+		require.NoError(t, err)
+
+		p.expireInvitations(inv.ExpireAt - 1)
+		assert.Len(t, p.API.(*fakeAPI).posts, 1)
+
+		p.expireInvitations(inv.ExpireAt)
+		require.Len(t, p.API.(*fakeAPI).posts, 2)
+		assert.Equal(t, inv.PostID, p.API.(*fakeAPI).posts[1].Id)
+		assert.Empty(t, p.API.(*fakeAPI).kv[KeyPendingInvitations])
+
+		_, err = p.takeInvitation(inv.ID, "user2")
+		assert.ErrorIs(t, err, ErrInvitationNotFound)
+	})
+}
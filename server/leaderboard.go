@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Leaderboard maps user IDs to their results during a period.
+type Leaderboard map[string]*GameRecord
+
+// leaderboardKey returns the key of the leaderboard of period that contains t.
+// Weeks follow ISO 8601 and both weeks and months are in UTC.
+func leaderboardKey(period string, t time.Time) (string, bool) {
+	t = t.UTC()
+	switch period {
+	case LeaderboardPeriodAll, "":
+		return KeyLeaderboard, true
+	case LeaderboardPeriodMonth:
+		return fmt.Sprintf("%s%s_%s", KeyPrefixLeaderboard, LeaderboardPeriodMonth, t.Format("2006-01")), true
+	case LeaderboardPeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%s%s_%d-W%02d", KeyPrefixLeaderboard, LeaderboardPeriodWeek, year, week), true
+	default:
+		return "", false
+	}
+}
+
+// decodeLeaderboard reads a stored leaderboard. Leaderboards stored before
+// they held whole records map user IDs to their number of wins.
+func decodeLeaderboard(data []byte) (Leaderboard, error) {
+	leaderboard := Leaderboard{}
+	if len(data) == 0 {
+		return leaderboard, nil
+	}
+
+	raw := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for userID, value := range raw {
+		record := &GameRecord{}
+		var wins int
+		if json.Unmarshal(value, &wins) == nil {
+			record.Wins = wins
+			record.GamesPlayed = wins
+		} else if err := json.Unmarshal(value, record); err != nil {
+			return nil, err
+		}
+		leaderboard[userID] = record
+	}
+
+	return leaderboard, nil
+}
+
+// getLeaderboard returns the current leaderboard of period.
+func (p *Plugin) getLeaderboard(period string) (Leaderboard, error) {
+	key, ok := leaderboardKey(period, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard period %q", period)
+	}
+
+	var data []byte
+	err := p.mm.KV.Get(key, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeLeaderboard(data)
+}
+
+// updateLeaderboards atomically records the results of a game finished at t,
+// by user ID, in the leaderboards of every period.
+func (p *Plugin) updateLeaderboards(results map[string]string, t time.Time) error {
+	for _, period := range []string{LeaderboardPeriodAll, LeaderboardPeriodMonth, LeaderboardPeriodWeek} {
+		key, _ := leaderboardKey(period, t)
+		err := p.mm.KV.SetAtomicWithRetries(key, func(oldValue []byte) (interface{}, error) {
+			leaderboard, err := decodeLeaderboard(oldValue)
+			if err != nil {
+				return nil, err
+			}
+
+			for userID, result := range results {
+				if leaderboard[userID] == nil {
+					leaderboard[userID] = &GameRecord{}
+				}
+				leaderboard[userID].AddGame(result)
+			}
+
+			return leaderboard, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rank returns the user IDs of the leaderboard sorted by wins, then win rate,
+// then games played.
+func (l Leaderboard) Rank() []string {
+	userIDs := make([]string, 0, len(l))
+	for userID := range l {
+		userIDs = append(userIDs, userID)
+	}
+
+	sort.Slice(userIDs, func(i, j int) bool {
+		a, b := l[userIDs[i]], l[userIDs[j]]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.WinRate() != b.WinRate() {
+			return a.WinRate() > b.WinRate()
+		}
+		if a.GamesPlayed != b.GamesPlayed {
+			return a.GamesPlayed > b.GamesPlayed
+		}
+		return userIDs[i] < userIDs[j]
+	})
+
+	return userIDs
+}
+
+// filterTeamMembers returns the users of userIDs that belong to teamID, in the
+// same order.
+func (p *Plugin) filterTeamMembers(teamID string, userIDs []string) []string {
+	members := []string{}
+	for _, userID := range userIDs {
+		member, err := p.mm.Team.GetMember(teamID, userID)
+		if err != nil || member.DeleteAt != 0 {
+			continue
+		}
+		members = append(members, userID)
+	}
+
+	return members
+}
+
+// getRankedLeaderboard returns a page of the leaderboard of period, only
+// counting the members of teamID if it is not empty.
+func (p *Plugin) getRankedLeaderboard(period, teamID string, page, perPage int) (*LeaderboardResponse, error) {
+	leaderboard, err := p.getLeaderboard(period)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := leaderboard.Rank()
+	if teamID != "" {
+		userIDs = p.filterTeamMembers(teamID, userIDs)
+	}
+
+	if period == "" {
+		period = LeaderboardPeriodAll
+	}
+	resp := &LeaderboardResponse{
+		Period:  period,
+		TeamID:  teamID,
+		Total:   len(userIDs),
+		Entries: []LeaderboardEntry{},
+	}
+
+	start := page * perPage
+	if start >= len(userIDs) {
+		return resp, nil
+	}
+	end := start + perPage
+	if end > len(userIDs) {
+		end = len(userIDs)
+	}
+
+	for i := start; i < end; i++ {
+		record := leaderboard[userIDs[i]]
+		resp.Entries = append(resp.Entries, LeaderboardEntry{
+			Rank:          i + 1,
+			UserID:        userIDs[i],
+			Username:      p.getUsername(userIDs[i]),
+			Wins:          record.Wins,
+			Losses:        record.Losses,
+			Draws:         record.Draws,
+			GamesPlayed:   record.GamesPlayed,
+			WinRate:       record.WinRate(),
+			BestWinStreak: record.BestWinStreak,
+		})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderboardKey(t *testing.T) {
+	at := time.Date(2021, time.January, 2, 12, 0, 0, 0, time.UTC)
+
+	key, ok := leaderboardKey(LeaderboardPeriodAll, at)
+	require.True(t, ok)
+	assert.Equal(t, KeyLeaderboard, key)
+
+	key, ok = leaderboardKey(LeaderboardPeriodMonth, at)
+	require.True(t, ok)
+	assert.Equal(t, "leaderboard_month_2021-01", key)
+
+	key, ok = leaderboardKey(LeaderboardPeriodWeek, at)
+	require.True(t, ok)
+	assert.Equal(t, "leaderboard_week_2020-W53", key)
+
+	_, ok = leaderboardKey("year", at)
+	assert.False(t, ok)
+}
+
+func TestUpdateLeaderboards(t *testing.T) {
+	p := newTestPlugin(t)
+
+	_, err := p.mm.KV.Set(KeyLeaderboard, map[string]int{"user3": 5})
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, p.updateLeaderboards(map[string]string{"user1": GameResultWin, "user2": GameResultLoss}, now))
+	require.NoError(t, p.updateLeaderboards(map[string]string{"user1": GameResultLoss, "user2": GameResultWin}, now))
+	require.NoError(t, p.updateLeaderboards(map[string]string{"user1": GameResultWin, "user2": GameResultLoss}, now))
+
+	resp, err := p.getRankedLeaderboard(LeaderboardPeriodAll, "", 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, resp.Total)
+	require.Len(t, resp.Entries, 2)
+	assert.Equal(t, LeaderboardEntry{Rank: 1, UserID: "user3", Username: "user3", Wins: 5, GamesPlayed: 5, WinRate: 1}, resp.Entries[0])
+	assert.Equal(t, LeaderboardEntry{Rank: 2, UserID: "user1", Username: "user1", Wins: 2, Losses: 1, GamesPlayed: 3, WinRate: 2.0 / 3, BestWinStreak: 1}, resp.Entries[1])
+
+	resp, err = p.getRankedLeaderboard(LeaderboardPeriodWeek, "", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, "user1", resp.Entries[0].UserID)
+	assert.Equal(t, "user2", resp.Entries[1].UserID)
+	assert.Equal(t, 1, resp.Entries[1].Wins)
+}
@@ -35,7 +35,178 @@ const manifestStr = `
   "settings_schema": {
     "header": "",
     "footer": "",
-    "settings": []
+    "settings": [
+      {
+        "key": "DefaultDifficulty",
+        "display_name": "Default board size:",
+        "type": "dropdown",
+        "help_text": "Board size of the games started without choosing one.",
+        "placeholder": "",
+        "default": "easy",
+        "options": [
+          {
+            "display_name": "Easy (12 cards)",
+            "value": "easy"
+          },
+          {
+            "display_name": "Medium (20 cards)",
+            "value": "medium"
+          },
+          {
+            "display_name": "Hard (36 cards)",
+            "value": "hard"
+          }
+        ]
+      },
+      {
+        "key": "MatchGrantsExtraTurn",
+        "display_name": "Matching a pair grants an extra turn:",
+        "type": "bool",
+        "help_text": "When true, players keep the turn after matching a pair. When false, the turn passes to the next player after every pair. Only applies to new games.",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "MaxGamesPerUser",
+        "display_name": "Maximum unfinished games per user:",
+        "type": "number",
+        "help_text": "Number of unfinished games a user can take part in at once. Set to 0 for no limit.",
+        "placeholder": "",
+        "default": 0
+      },
+      {
+        "key": "BotMemory",
+        "display_name": "Memory bot skill:",
+        "type": "dropdown",
+        "help_text": "How well the memory bot remembers the cards revealed during its games. Only applies to new games.",
+        "placeholder": "",
+        "default": "medium",
+        "options": [
+          {
+            "display_name": "Easy (remembers few cards)",
+            "value": "easy"
+          },
+          {
+            "display_name": "Medium (remembers most cards)",
+            "value": "medium"
+          },
+          {
+            "display_name": "Perfect (remembers every card)",
+            "value": "perfect"
+          }
+        ]
+      },
+      {
+        "key": "DailyChallengeChannelID",
+        "display_name": "Daily challenge results channel ID:",
+        "type": "text",
+        "help_text": "ID of the channel the memory bot posts the results of the daily challenge to, the day after. Leave empty to not post the results.",
+        "placeholder": "",
+        "default": ""
+      },
+      {
+        "key": "EnableDirectMessages",
+        "display_name": "Enable games in direct messages:",
+        "type": "bool",
+        "help_text": "",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "EnableGroupMessages",
+        "display_name": "Enable games in group messages:",
+        "type": "bool",
+        "help_text": "",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "EnablePrivateChannels",
+        "display_name": "Enable games in private channels:",
+        "type": "bool",
+        "help_text": "",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "EnablePublicChannels",
+        "display_name": "Enable games in public channels:",
+        "type": "bool",
+        "help_text": "",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "EnableNotifications",
+        "display_name": "Enable bot notifications:",
+        "type": "bool",
+        "help_text": "When true, the memory bot sends direct messages to players about their games.",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "EnableBadges",
+        "display_name": "Enable badges:",
+        "type": "bool",
+        "help_text": "When true, players are granted badges through the Badges plugin.",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "EnableBoardPosts",
+        "display_name": "Enable board posts:",
+        "type": "bool",
+        "help_text": "When true, the memory bot posts the board of every game with a button per card, so that games can be played from clients without the plugin, like the mobile apps.",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "EnableSummaryPosts",
+        "display_name": "Enable game summary posts:",
+        "type": "bool",
+        "help_text": "When true, the memory bot posts the result of every finished game in its channel, with a picture of the final board and a button to play again. Daily challenges are not posted.",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "TurnTimeoutMinutes",
+        "display_name": "Turn time limit (minutes):",
+        "type": "number",
+        "help_text": "Time a player has to move before the turn timeout action applies. Players are reminded to move halfway through. Set to 0 to disable turn time limits.",
+        "placeholder": "",
+        "default": 0
+      },
+      {
+        "key": "TurnTimeoutAction",
+        "display_name": "Turn timeout action:",
+        "type": "dropdown",
+        "help_text": "What happens when a player runs out of time.",
+        "placeholder": "",
+        "default": "remind",
+        "options": [
+          {
+            "display_name": "Only remind the player",
+            "value": "remind"
+          },
+          {
+            "display_name": "Skip the turn",
+            "value": "skip"
+          },
+          {
+            "display_name": "Forfeit the game",
+            "value": "forfeit"
+          }
+        ]
+      },
+      {
+        "key": "AbandonedGameDays",
+        "display_name": "Remove abandoned games after (days):",
+        "type": "number",
+        "help_text": "Games without any move for this many days are removed without counting towards the stats. Set to 0 to keep games forever.",
+        "placeholder": "",
+        "default": 7
+      }
+    ]
   }
 }
 `
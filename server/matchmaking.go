@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// matchmakingExpiry is how long a player waits in the queue for an opponent.
+const matchmakingExpiry = 15 * time.Minute
+
+var (
+	ErrAlreadyQueued = errors.New("already waiting for an opponent")
+	ErrNotQueued     = errors.New("not waiting for an opponent")
+	ErrNotTeamMember = errors.New("not a member of the team")
+)
+
+// MatchmakingEntry is a player waiting for an opponent. Players are only
+// paired with players of the same TeamID and Difficulty, and whose rating is
+// within both of their rating windows.
+type MatchmakingEntry struct {
+	UserID       string  `json:"userID"`
+	TeamID       string  `json:"teamID"`
+	Difficulty   string  `json:"difficulty"`
+	Rating       float64 `json:"rating"`
+	RatingWindow float64 `json:"ratingWindow"`
+	JoinAt       int64   `json:"joinAt"`
+}
+
+// Expired reports whether the player stopped waiting at time now, in
+// milliseconds.
+func (e *MatchmakingEntry) Expired(now int64) bool {
+	return now >= e.JoinAt+matchmakingExpiry.Milliseconds()
+}
+
+// Accepts returns whether the player would play against other. A zero rating
+// window accepts any rating.
+func (e *MatchmakingEntry) Accepts(other *MatchmakingEntry) bool {
+	if e.TeamID != other.TeamID || e.Difficulty != other.Difficulty {
+		return false
+	}
+
+	return e.RatingWindow <= 0 || math.Abs(e.Rating-other.Rating) <= e.RatingWindow
+}
+
+// updateMatchmakingQueue atomically applies update to the players waiting for
+// an opponent, in the order they joined.
+func (p *Plugin) updateMatchmakingQueue(update func(queue []*MatchmakingEntry) ([]*MatchmakingEntry, error)) error {
+	return p.mm.KV.SetAtomicWithRetries(KeyMatchmakingQueue, func(oldValue []byte) (interface{}, error) {
+		queue := []*MatchmakingEntry{}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, &queue); err != nil {
+				return nil, err
+			}
+		}
+
+		queue, err := update(queue)
+		if err != nil {
+			return nil, err
+		}
+		if len(queue) == 0 {
+			return nil, nil
+		}
+
+		return queue, nil
+	})
+}
+
+// joinMatchmaking pairs userID with the first compatible player waiting for
+// an opponent and starts their game. When nobody matches, userID waits in the
+// queue and a nil game is returned.
+func (p *Plugin) joinMatchmaking(userID string, req *MatchmakingRequest) (*Game, error) {
+	config := p.getConfiguration()
+	if !config.IsChannelTypeEnabled(model.CHANNEL_DIRECT) {
+		return nil, ErrChannelTypeDisabled
+	}
+
+	if req.TeamID != "" {
+		member, err := p.mm.Team.GetMember(req.TeamID, userID)
+		if err != nil || member.DeleteAt != 0 {
+			return nil, ErrNotTeamMember
+		}
+	}
+
+	err := p.checkGameLimit(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rating, err := p.getRating(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get rating")
+	}
+
+	difficulty := req.Difficulty
+	if difficulty == "" {
+		difficulty = config.DefaultDifficulty
+	}
+
+	now := model.GetMillis()
+	entry := &MatchmakingEntry{
+		UserID:       userID,
+		TeamID:       req.TeamID,
+		Difficulty:   difficulty,
+		Rating:       rating.Rating,
+		RatingWindow: req.RatingWindow,
+		JoinAt:       now,
+	}
+
+	var opponent *MatchmakingEntry
+	var dropped []*MatchmakingEntry
+	err = p.updateMatchmakingQueue(func(queue []*MatchmakingEntry) ([]*MatchmakingEntry, error) {
+		opponent = nil
+		dropped = nil
+		kept := []*MatchmakingEntry{}
+		for _, waiting := range queue {
+			if waiting.UserID == userID {
+				if !waiting.Expired(now) {
+					return nil, ErrAlreadyQueued
+				}
+				continue
+			}
+			if opponent == nil && !waiting.Expired(now) && waiting.Accepts(entry) && entry.Accepts(waiting) {
+				// Players may have started other games while waiting.
+				err := p.checkGameLimit(waiting.UserID)
+				if errors.Is(err, ErrTooManyGames) {
+					dropped = append(dropped, waiting)
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				opponent = waiting
+				continue
+			}
+			kept = append(kept, waiting)
+		}
+
+		if opponent == nil {
+			kept = append(kept, entry)
+		}
+
+		return kept, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, waiting := range dropped {
+		p.stopWaiting(waiting.UserID, fmt.Sprintf("You stopped waiting for an opponent to play memory, as you cannot take part in more than %d unfinished games.", config.MaxGamesPerUser))
+	}
+
+	if opponent == nil {
+		return nil, nil
+	}
+
+	game, err := p.startMatch(opponent, entry)
+	if err != nil {
+		// Give the opponent their place back rather than dropping them.
+		_ = p.updateMatchmakingQueue(func(queue []*MatchmakingEntry) ([]*MatchmakingEntry, error) {
+			return append([]*MatchmakingEntry{opponent}, queue...), nil
+		})
+		return nil, err
+	}
+
+	return game, nil
+}
+
+// startMatch starts the game between two paired players in their direct
+// message and tells both of them about it.
+func (p *Plugin) startMatch(first, second *MatchmakingEntry) (*Game, error) {
+	c, err := p.mm.Channel.GetDirect(first.UserID, second.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get direct channel")
+	}
+
+	game, err := p.NewGame([]string{first.UserID, second.UserID}, c.Id, first.Difficulty)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create game")
+	}
+
+	err = game.Begin(first.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot begin game")
+	}
+
+	err = p.addGame(game)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, player := range game.Players {
+		opponent := first.UserID
+		if player == first.UserID {
+			opponent = second.UserID
+		}
+
+		p.mm.Frontend.PublishWebSocketEvent("match_found", map[string]interface{}{
+			"gID":       game.GID,
+			"channelID": game.ChannelID,
+			"opponent":  opponent,
+		}, &model.WebsocketBroadcast{UserId: player})
+
+		p.notify(player, fmt.Sprintf("You were matched with @%s. Your memory game is waiting in your direct messages with them.", p.getUsername(opponent)))
+	}
+
+	return game, nil
+}
+
+// leaveMatchmaking stops userID from waiting for an opponent.
+func (p *Plugin) leaveMatchmaking(userID string) error {
+	return p.updateMatchmakingQueue(func(queue []*MatchmakingEntry) ([]*MatchmakingEntry, error) {
+		kept := []*MatchmakingEntry{}
+		for _, waiting := range queue {
+			if waiting.UserID != userID {
+				kept = append(kept, waiting)
+			}
+		}
+
+		if len(kept) == len(queue) {
+			return nil, ErrNotQueued
+		}
+
+		return kept, nil
+	})
+}
+
+// expireMatchmaking removes the players that waited too long for an opponent
+// at time now, in milliseconds, and tells them.
+func (p *Plugin) expireMatchmaking(now int64) {
+	var expired []*MatchmakingEntry
+	err := p.updateMatchmakingQueue(func(queue []*MatchmakingEntry) ([]*MatchmakingEntry, error) {
+		expired = nil
+		kept := []*MatchmakingEntry{}
+		for _, waiting := range queue {
+			if waiting.Expired(now) {
+				expired = append(expired, waiting)
+			} else {
+				kept = append(kept, waiting)
+			}
+		}
+		return kept, nil
+	})
+	if err != nil {
+		p.mm.Log.Warn("Cannot expire matchmaking", "err", err.Error())
+		return
+	}
+
+	for _, waiting := range expired {
+		p.stopWaiting(waiting.UserID, fmt.Sprintf("Nobody was found to play memory with you in %d minutes. Try again later.", int(matchmakingExpiry/time.Minute)))
+	}
+}
+
+// stopWaiting tells userID, who was removed from the matchmaking queue, why.
+func (p *Plugin) stopWaiting(userID, message string) {
+	p.mm.Frontend.PublishWebSocketEvent("matchmaking_expired", map[string]interface{}{}, &model.WebsocketBroadcast{UserId: userID})
+	p.notify(userID, message)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchmaking(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableDirectMessages: true, EnableNotifications: true, DefaultDifficulty: DifficultyEasy})
+
+	game, err := p.joinMatchmaking("user1", &MatchmakingRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, game)
+
+	_, err = p.joinMatchmaking("user1", &MatchmakingRequest{})
+	assert.ErrorIs(t, err, ErrAlreadyQueued)
+
+	game, err = p.joinMatchmaking("user2", &MatchmakingRequest{Difficulty: DifficultyHard})
+	require.NoError(t, err)
+	assert.Nil(t, game)
+
+	game, err = p.joinMatchmaking("user3", &MatchmakingRequest{Difficulty: DifficultyEasy})
+	require.NoError(t, err)
+	require.NotNil(t, game)
+	assert.Equal(t, "user1__user3", game.ChannelID)
+	assert.ElementsMatch(t, []string{"user1", "user3"}, game.Players)
+	assert.True(t, game.Started)
+	assert.Len(t, p.API.(*fakeAPI).posts, 2)
+
+	stored, err := p.getGame(game.GID)
+	require.NoError(t, err)
+	assert.Equal(t, game.CardValues, stored.CardValues)
+
+	require.NoError(t, p.leaveMatchmaking("user2"))
+	assert.ErrorIs(t, p.leaveMatchmaking("user2"), ErrNotQueued)
+	assert.ErrorIs(t, p.leaveMatchmaking("user1"), ErrNotQueued)
+
+	_, err = p.joinMatchmaking("user4", &MatchmakingRequest{})
+	require.NoError(t, err)
+	p.expireMatchmaking(model.GetMillis() + matchmakingExpiry.Milliseconds())
+	assert.ErrorIs(t, p.leaveMatchmaking("user4"), ErrNotQueued)
+}
+
+func TestMatchmakingGameLimit(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableDirectMessages: true, DefaultDifficulty: DifficultyEasy, MaxGamesPerUser: 1})
+	api := p.API.(*fakeAPI)
+
+	game, err := p.joinMatchmaking("user1", &MatchmakingRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, game)
+
+	// user1 starts another game while waiting.
+	newTestGame(t, p)
+	require.NoError(t, p.addActiveGame("game"))
+
+	game, err = p.joinMatchmaking("user3", &MatchmakingRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, game)
+	assert.Empty(t, api.posts)
+
+	assert.ErrorIs(t, p.leaveMatchmaking("user1"), ErrNotQueued)
+	require.NoError(t, p.leaveMatchmaking("user3"))
+}
+
+func TestMatchmakingEntryAccepts(t *testing.T) {
+	entry := &MatchmakingEntry{Difficulty: DifficultyEasy, Rating: 1500, RatingWindow: 100}
+
+	assert.True(t, entry.Accepts(&MatchmakingEntry{Difficulty: DifficultyEasy, Rating: 1600}))
+	assert.False(t, entry.Accepts(&MatchmakingEntry{Difficulty: DifficultyEasy, Rating: 1601}))
+	assert.False(t, entry.Accepts(&MatchmakingEntry{Difficulty: DifficultyHard, Rating: 1500}))
+	assert.False(t, entry.Accepts(&MatchmakingEntry{Difficulty: DifficultyEasy, TeamID: "team", Rating: 1500}))
+
+	entry.RatingWindow = 0
+	assert.True(t, entry.Accepts(&MatchmakingEntry{Difficulty: DifficultyEasy, Rating: 2500}))
+}
@@ -1,44 +1,620 @@
 package main
 
+import "time"
+
 type FlipCardRequest struct {
 	Index int `json:"index"`
+	Seq   int `json:"seq"`
 }
 
 type FlipCardResponse struct {
-	Value string `json:"value"`
+	Value         string `json:"value"`
+	Seq           int    `json:"seq"`
+	Turn          bool   `json:"turn"`
+	CurrentPlayer string `json:"currentPlayer"`
+	Scores        []int  `json:"scores"`
 }
 
+// StartGameRequest asks for a game in ChannelID. Solo games are played alone
+// by the user that starts them.
 type StartGameRequest struct {
-	ChannelID string `json:"channelID"`
+	ChannelID  string `json:"channelID"`
+	Difficulty string `json:"difficulty"`
+	Solo       bool   `json:"solo"`
 }
 
+// StartGameResponse describes the game that was created. Games in direct
+// messages wait for the opponent to accept an invitation first, in which case
+// only InvitationID is set.
 type StartGameResponse struct {
-	GID  string `json:"gID"`
-	Turn bool   `json:"turn"`
+	GID          string `json:"gID"`
+	Turn         bool   `json:"turn"`
+	InvitationID string `json:"invitationID,omitempty"`
+}
+
+// BoardResponse is the board of a game rendered in Format.
+type BoardResponse struct {
+	GID    string `json:"gID"`
+	Format string `json:"format"`
+	Board  string `json:"board"`
+}
+
+// MatchmakingRequest asks for an opponent. TeamID limits the opponents to the
+// members of a team, and RatingWindow to the players whose rating differs by
+// at most that much. Both are optional.
+type MatchmakingRequest struct {
+	TeamID       string  `json:"teamID"`
+	Difficulty   string  `json:"difficulty"`
+	RatingWindow float64 `json:"ratingWindow"`
+}
+
+// MatchmakingResponse tells whether the player was paired right away, in
+// which case the game and its channel are set, or is waiting in the queue.
+type MatchmakingResponse struct {
+	Queued    bool   `json:"queued"`
+	GID       string `json:"gID,omitempty"`
+	ChannelID string `json:"channelID,omitempty"`
 }
 
+// GetGameResponse describes a game from the point of view of one user.
+// Players and Scores are in turn order.
 type GetGameResponse struct {
 	Values        []string `json:"cards"`
 	Turn          bool     `json:"turn"`
 	LastFlipped   int      `json:"lastFlipped"`
-	OpponentName  string   `json:"opponentName"`
 	MyScore       int      `json:"myScore"`
-	OpponentScore int      `json:"opponentScore"`
+	Rows          int      `json:"rows"`
+	Columns       int      `json:"columns"`
+	Seq           int      `json:"seq"`
+	Started       bool     `json:"started"`
+	IsOwner       bool     `json:"isOwner"`
+	CurrentPlayer string   `json:"currentPlayer"`
+	Players       []string `json:"players"`
+	Scores        []int    `json:"scores"`
+	DrawOffers    []string `json:"drawOffers"`
+	Solo          bool     `json:"solo"`
+	Moves         int      `json:"moves"`
+	CreateAt      int64    `json:"createAt"`
+	SeedHash      string   `json:"seedHash,omitempty"`
 }
 
+// Game holds the state of a memory game. Cards are stored row by row, so the
+// card at row r and column c is at index r*Columns+c.
 type Game struct {
-	GID           string
-	CardValues    []string
-	CardFlipped   []bool
-	LastFlipped   int
-	CurrentPlayer string
-	OtherPlayer   string
-	Scores        map[string]int
-	Streak        int
+	GID         string
+	ChannelID   string
+	CardValues  []string
+	CardFlipped []bool
+	Rows        int
+	Columns     int
+	LastFlipped int
+	// Owner is the user that created the game and the only one that can
+	// begin it.
+	Owner string
+	// Players holds the players in turn order, and Turn the index of the
+	// player that has to move.
+	Players []string
+	Turn    int
+	// Started is false while players can still join or leave the game.
+	Started bool
+	Scores  map[string]int
+	Streak  int
+	// Resignations holds the players that gave up the game, in order.
+	Resignations []string
+	// Over is set once the game does not accept any more moves, and
+	// Abandoned when it ended because nobody played it for too long.
+	Over      bool
+	Abandoned bool
+	// Seq is incremented on every stored move. Moves must carry the sequence
+	// number they were based on so that stale moves can be rejected.
+	Seq int
+	// Events records every move of the game, so it can be replayed.
+	Events   []GameEvent
+	CreateAt int64
+	EndAt    int64
+	// ActiveAt is the last time a player acted on the game, and Reminded
+	// whether the current player was reminded to move since then.
+	ActiveAt int64
+	Reminded bool
+	// DrawOffers holds the players that agreed to end the game in a draw, in
+	// order, and Drawn whether the game ended that way.
+	DrawOffers []string
+	Drawn      bool
+	// PassTurnOnMatch makes the turn pass to the next player after every
+	// pair, instead of only after a mismatch.
+	PassTurnOnMatch bool
+	// StartOrder holds the players in the turn order the game began with.
+	StartOrder []string
+	// SeriesID identifies the series of rematches the game belongs to, and
+	// RematchID the game that was started as its rematch.
+	SeriesID  string
+	RematchID string
+	// BotMemory is the skill of the memory bot in games it plays, and empty
+	// in games between users.
+	BotMemory string
+	// BotKnownCards holds the cards the bot remembers, and BotSeenEvents the
+	// number of events it has looked at.
+	BotKnownCards []int
+	BotSeenEvents int
+	// Solo is set for practice games cleared by a single player.
+	Solo bool
+	// Daily is the date of the daily challenge the game was played on, if
+	// any. Daily challenge games are solo games.
+	Daily string
+	// Seed lays out the cards and shuffles the players. SeedHash commits to
+	// it when the game starts, so that players can check the layout against
+	// the seed revealed once the game is over.
+	Seed     int64
+	SeedHash string
+	// BoardPostID is the bot post showing the board with a button per card,
+	// if board posts were enabled when the game was created.
+	BoardPostID string
+
+	// Two-player games stored before turn orders existed.
+	LegacyCurrentPlayer string `json:"CurrentPlayer,omitempty"`
+	LegacyOtherPlayer   string `json:"OtherPlayer,omitempty"`
+}
+
+// GameEvent is a single move of a game.
+type GameEvent struct {
+	Type   string `json:"type"`
+	UserID string `json:"userID"`
+	Index  int    `json:"index"`
+	Value  string `json:"value"`
+	At     int64  `json:"at"`
+}
+
+// HistoryEntry describes a finished game. Players and Scores include the
+// players that resigned. Winner is empty if the game was a draw.
+type HistoryEntry struct {
+	GID       string   `json:"gID"`
+	ChannelID string   `json:"channelID"`
+	Players   []string `json:"players"`
+	Scores    []int    `json:"scores"`
+	Winner    string   `json:"winner"`
+	Rows      int      `json:"rows"`
+	Columns   int      `json:"columns"`
+	CreateAt  int64    `json:"createAt"`
+	EndAt     int64    `json:"endAt"`
+	SeedHash  string   `json:"seedHash,omitempty"`
+	// Seed is only set once it is revealed, in base 10.
+	Seed string `json:"seed,omitempty"`
+}
+
+// ReplayMove is a move of a finished game as sent to clients.
+type ReplayMove struct {
+	Type   string `json:"type"`
+	Player string `json:"player"`
+	Index  int    `json:"index"`
+	Value  string `json:"value"`
+	At     int64  `json:"at"`
+}
+
+// ReplayResponse holds everything needed to replay a finished game.
+type ReplayResponse struct {
+	HistoryEntry
+	Cards []string     `json:"cards"`
+	Moves []ReplayMove `json:"moves"`
+}
+
+// VerifySeedResponse checks the layout of a finished game against its
+// revealed seed. Seed is in base 10.
+type VerifySeedResponse struct {
+	GID        string   `json:"gID"`
+	Seed       string   `json:"seed"`
+	SeedHash   string   `json:"seedHash"`
+	HashMatch  bool     `json:"hashMatch"`
+	Cards      []string `json:"cards"`
+	CardsMatch bool     `json:"cardsMatch"`
+}
+
+// GameSummary describes one of the games of a channel.
+type GameSummary struct {
+	GID           string   `json:"gID"`
+	Started       bool     `json:"started"`
+	IsPlayer      bool     `json:"isPlayer"`
+	CurrentPlayer string   `json:"currentPlayer"`
+	Players       []string `json:"players"`
+	Rows          int      `json:"rows"`
+	Columns       int      `json:"columns"`
+	Solo          bool     `json:"solo"`
+	// Daily is the date of the daily challenge of the game, if any.
+	Daily string `json:"daily,omitempty"`
+}
+
+// GameRecord counts the results of a player over a number of games.
+type GameRecord struct {
+	Wins        int `json:"wins"`
+	Losses      int `json:"losses"`
+	Draws       int `json:"draws"`
+	GamesPlayed int `json:"gamesPlayed"`
+	// WinStreak is the number of games won in a row, and BestWinStreak the
+	// longest such run.
+	WinStreak     int `json:"winStreak"`
+	BestWinStreak int `json:"bestWinStreak"`
 }
 
+// AddGame records the result of a finished game, one of the GameResult
+// constants.
+func (r *GameRecord) AddGame(result string) {
+	r.GamesPlayed++
+	switch result {
+	case GameResultWin:
+		r.Wins++
+		r.WinStreak++
+		if r.WinStreak > r.BestWinStreak {
+			r.BestWinStreak = r.WinStreak
+		}
+		return
+	case GameResultDraw:
+		r.Draws++
+	default:
+		r.Losses++
+	}
+	r.WinStreak = 0
+}
+
+// WinRate returns the share of games won, between 0 and 1.
+func (r *GameRecord) WinRate() float64 {
+	if r.GamesPlayed == 0 {
+		return 0
+	}
+
+	return float64(r.Wins) / float64(r.GamesPlayed)
+}
+
+// PlayerStats holds the lifetime results of a player.
 type PlayerStats struct {
-	Wins int
+	GameRecord
+	PairsMatched int
+	// LongestMatchStreak is the most pairs matched in a row in a single game.
+	LongestMatchStreak int
+	// Moves is the number of pairs of cards turned over.
+	Moves int
+	// Opponents holds the record against each opponent, by user ID.
+	Opponents map[string]*GameRecord
+}
+
+// RecordGame adds a finished game to the stats of userID.
+func (s *PlayerStats) RecordGame(game *Game, userID string) {
+	s.AddGame(game.Result(userID))
+	s.PairsMatched += game.Scores[userID]
+
+	moves, matchStreaks := game.PlayerMoves()
+	s.Moves += moves[userID]
+	if matchStreaks[userID] > s.LongestMatchStreak {
+		s.LongestMatchStreak = matchStreaks[userID]
+	}
+
+	if s.Opponents == nil {
+		s.Opponents = map[string]*GameRecord{}
+	}
+	for _, opponent := range game.Participants() {
+		if opponent == userID {
+			continue
+		}
+		if s.Opponents[opponent] == nil {
+			s.Opponents[opponent] = &GameRecord{}
+		}
+		s.Opponents[opponent].AddGame(game.HeadToHead(userID, opponent))
+	}
+}
+
+// AverageMoves returns the average number of pairs turned over per game.
+func (s *PlayerStats) AverageMoves() float64 {
+	if s.GamesPlayed == 0 {
+		return 0
+	}
+
+	return float64(s.Moves) / float64(s.GamesPlayed)
+}
+
+// Rating is the Elo rating of a player in games between users. Ratings are
+// provisional until the player finished ProvisionalGames rated games.
+type Rating struct {
+	Rating float64
+	Games  int
+	Peak   float64
+	// History holds the latest rating changes, oldest first.
+	History []RatingChange
+}
+
+// RatingChange is the change of the rating of a player after a game.
+type RatingChange struct {
+	GID    string  `json:"gID"`
+	Rating float64 `json:"rating"`
+	Delta  float64 `json:"delta"`
+	At     int64   `json:"at"`
+}
+
+// Provisional returns whether the rating is still settling.
+func (r *Rating) Provisional() bool {
+	return r.Games < ProvisionalGames
+}
+
+// Apply changes the rating by delta after the game gID finished at time at.
+// Applying the same game twice has no effect.
+func (r *Rating) Apply(gID string, delta float64, at int64) {
+	if len(r.History) > 0 && r.History[len(r.History)-1].GID == gID {
+		return
+	}
+
+	r.Rating += delta
+	r.Games++
+	if r.Rating > r.Peak {
+		r.Peak = r.Rating
+	}
+
+	r.History = append(r.History, RatingChange{
+		GID:    gID,
+		Rating: r.Rating,
+		Delta:  delta,
+		At:     at,
+	})
+	if len(r.History) > MaxHistorySize {
+		r.History = r.History[len(r.History)-MaxHistorySize:]
+	}
+}
+
+// RatingResponse describes the rating of a player and how it changed.
+type RatingResponse struct {
+	UserID      string         `json:"userID"`
+	Username    string         `json:"username"`
+	Rating      float64        `json:"rating"`
+	Provisional bool           `json:"provisional"`
+	Games       int            `json:"games"`
+	Peak        float64        `json:"peak"`
+	History     []RatingChange `json:"history"`
+}
+
+// OpponentRecord is the record of a player against one opponent as sent to
+// clients.
+type OpponentRecord struct {
+	UserID      string `json:"userID"`
+	Username    string `json:"username"`
+	Wins        int    `json:"wins"`
+	Losses      int    `json:"losses"`
+	Draws       int    `json:"draws"`
+	GamesPlayed int    `json:"gamesPlayed"`
+}
+
+// PlayerStatsResponse describes the stats of a player. Opponents are sorted
+// by number of games played against them. Games against the memory bot are
+// only counted in BotGames.
+type PlayerStatsResponse struct {
+	UserID             string           `json:"userID"`
+	Username           string           `json:"username"`
+	Wins               int              `json:"wins"`
+	Losses             int              `json:"losses"`
+	Draws              int              `json:"draws"`
+	GamesPlayed        int              `json:"gamesPlayed"`
+	WinRate            float64          `json:"winRate"`
+	BestWinStreak      int              `json:"bestWinStreak"`
+	PairsMatched       int              `json:"pairsMatched"`
+	LongestMatchStreak int              `json:"longestMatchStreak"`
+	AverageMoves       float64          `json:"averageMoves"`
+	Opponents          []OpponentRecord `json:"opponents"`
+	BotGames           GameRecord       `json:"botGames"`
+}
+
+// LeaderboardEntry is a ranked player as sent to clients.
+type LeaderboardEntry struct {
+	Rank          int     `json:"rank"`
+	UserID        string  `json:"userID"`
+	Username      string  `json:"username"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	Draws         int     `json:"draws"`
+	GamesPlayed   int     `json:"gamesPlayed"`
+	WinRate       float64 `json:"winRate"`
+	BestWinStreak int     `json:"bestWinStreak"`
+}
+
+// LeaderboardResponse holds a page of a leaderboard. Total is the number of
+// ranked players in the whole leaderboard.
+type LeaderboardResponse struct {
+	Period  string             `json:"period"`
+	TeamID  string             `json:"teamID,omitempty"`
+	Total   int                `json:"total"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// Series is the running score of a game and of its successive rematches.
+type Series struct {
+	ID      string         `json:"id"`
+	Players []string       `json:"players"`
+	Wins    map[string]int `json:"wins"`
+	Draws   int            `json:"draws"`
+	Games   int            `json:"games"`
+}
+
+// AddGame counts the result of a finished game of the series.
+func (s *Series) AddGame(game *Game) {
+	if len(s.Players) == 0 {
+		s.Players = game.Participants()
+	}
+	if s.Wins == nil {
+		s.Wins = map[string]int{}
+	}
+
+	s.Games++
+	winner := game.Winner()
+	if winner == "" {
+		s.Draws++
+		return
+	}
+	s.Wins[winner]++
+}
+
+type RematchResponse struct {
+	GID    string  `json:"gID"`
+	Turn   bool    `json:"turn"`
+	Series *Series `json:"series"`
+}
+
+// SoloResult is the result of a board cleared alone. Duration is in
+// milliseconds.
+type SoloResult struct {
+	GID      string `json:"gID"`
+	Moves    int    `json:"moves"`
+	Duration int64  `json:"duration"`
+	EndAt    int64  `json:"endAt"`
+}
+
+// Better returns whether r beats other, with fewer moves first and then a
+// shorter time.
+func (r *SoloResult) Better(other *SoloResult) bool {
+	if other == nil {
+		return true
+	}
+	if r.Moves != other.Moves {
+		return r.Moves < other.Moves
+	}
+	if r.Duration != other.Duration {
+		return r.Duration < other.Duration
+	}
+	return r.EndAt < other.EndAt
+}
+
+// SoloLeaderboardEntry is a ranked solo result as sent to clients.
+type SoloLeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	UserID   string `json:"userID"`
+	Username string `json:"username"`
+	SoloResult
+}
+
+type SoloLeaderboardResponse struct {
+	Difficulty string                 `json:"difficulty"`
+	Total      int                    `json:"total"`
+	Entries    []SoloLeaderboardEntry `json:"entries"`
+}
+
+// SoloStatsResponse holds the personal bests of a user, by difficulty.
+type SoloStatsResponse struct {
+	UserID   string                 `json:"userID"`
+	Username string                 `json:"username"`
+	Bests    map[string]*SoloResult `json:"bests"`
+}
+
+// DailyChallenge is the board every user can play once on a given day.
+type DailyChallenge struct {
+	Date       string
+	Seed       int64
+	Difficulty string
+	// Games holds the game each user started on the challenge, and Results
+	// the boards they cleared, by user ID.
+	Games   map[string]string
+	Results SoloLeaderboard
+	// Posted is set once the results were posted to the results channel.
+	Posted bool
+}
+
+// DailyStreak counts the consecutive days a user cleared the daily
+// challenge. Last is the date of the last challenge cleared.
+type DailyStreak struct {
+	Last    string `json:"last"`
+	Current int    `json:"current"`
+	Best    int    `json:"best"`
+}
+
+// Record counts the challenge of date in the streak. Clearing the challenge
+// of the day after the last one extends the streak, any other day starts a
+// new one.
+func (s *DailyStreak) Record(date string) {
+	if s.Last == date {
+		return
+	}
+
+	if s.Last != "" && s.Last == previousDailyDate(date) {
+		s.Current++
+	} else {
+		s.Current = 1
+	}
+	s.Last = date
+
+	if s.Current > s.Best {
+		s.Best = s.Current
+	}
+}
+
+// CurrentAt returns the length of the streak on date. A streak is kept
+// until the end of the day after the last challenge cleared.
+func (s *DailyStreak) CurrentAt(date string) int {
+	if s.Last == date || s.Last == previousDailyDate(date) {
+		return s.Current
+	}
+
+	return 0
+}
+
+// DailyChallengeResponse holds the ranking of a daily challenge and where
+// the requesting user stands.
+type DailyChallengeResponse struct {
+	Date       string                 `json:"date"`
+	Difficulty string                 `json:"difficulty"`
+	Total      int                    `json:"total"`
+	Entries    []SoloLeaderboardEntry `json:"entries"`
+	// GID is the game of the requesting user on the challenge, if any.
+	GID        string      `json:"gID,omitempty"`
+	Result     *SoloResult `json:"result,omitempty"`
+	Rank       int         `json:"rank,omitempty"`
+	Streak     int         `json:"streak"`
+	BestStreak int         `json:"bestStreak"`
+}
+
+// GetDailyDate returns the date of the daily challenge played at t.
+// Challenges change at midnight UTC.
+func GetDailyDate(t time.Time) string {
+	return t.UTC().Format(dailyDateLayout)
+}
+
+// previousDailyDate returns the date of the challenge before the one of
+// date.
+func previousDailyDate(date string) string {
+	t, err := time.Parse(dailyDateLayout, date)
+	if err != nil {
+		return ""
+	}
+
+	return GetDailyDate(t.AddDate(0, 0, -1))
+}
+
+type BoardSize struct {
+	Rows    int
+	Columns int
+}
+
+// Cards returns the number of cards on a board of this size.
+func (b BoardSize) Cards() int {
+	return b.Rows * b.Columns
+}
+
+// GetBoardSize returns the board dimensions for the given difficulty. An empty
+// difficulty defaults to the easy board.
+func GetBoardSize(difficulty string) (BoardSize, bool) {
+	switch difficulty {
+	case DifficultyEasy, "":
+		return BoardSize{Rows: 4, Columns: 3}, true
+	case DifficultyMedium:
+		return BoardSize{Rows: 5, Columns: 4}, true
+	case DifficultyHard:
+		return BoardSize{Rows: 6, Columns: 6}, true
+	default:
+		return BoardSize{}, false
+	}
+}
+
+// GetDifficulty returns the difficulty of a board with the given dimensions.
+func GetDifficulty(rows, columns int) (string, bool) {
+	for _, difficulty := range []string{DifficultyEasy, DifficultyMedium, DifficultyHard} {
+		size, _ := GetBoardSize(difficulty)
+		if size.Rows == rows && size.Columns == columns {
+			return difficulty, true
+		}
+	}
+
+	return "", false
 }
 
 func GetCardPool() []string {
@@ -49,5 +625,17 @@ func GetCardPool() []string {
 		"spadesAce",
 		"joker",
 		"heartsKing",
+		"diamondsKing",
+		"clubsKing",
+		"spadesKing",
+		"heartsQueen",
+		"diamondsQueen",
+		"clubsQueen",
+		"spadesQueen",
+		"heartsJack",
+		"diamondsJack",
+		"clubsJack",
+		"spadesJack",
+		"hearts10",
 	}
 }
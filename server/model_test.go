@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGameBoardSizes(t *testing.T) {
+	p := Plugin{}
+
+	for _, difficulty := range []string{"", DifficultyEasy, DifficultyMedium, DifficultyHard} {
+		t.Run(difficulty, func(t *testing.T) {
+			size, ok := GetBoardSize(difficulty)
+			require.True(t, ok)
+
+			game, err := p.NewGame([]string{"user1", "user2"}, "game", difficulty)
+			require.NoError(t, err)
+
+			assert.Equal(t, size.Rows, game.Rows)
+			assert.Equal(t, size.Columns, game.Columns)
+			assert.Len(t, game.CardValues, size.Cards())
+			assert.Len(t, game.CardFlipped, size.Cards())
+
+			counts := map[string]int{}
+			for _, value := range game.CardValues {
+				counts[value]++
+			}
+			assert.Len(t, counts, size.Cards()/2)
+			for value, count := range counts {
+				assert.Equal(t, 2, count, value)
+			}
+		})
+	}
+
+	_, err := p.NewGame([]string{"user1", "user2"}, "game", "impossible")
+	assert.Error(t, err)
+}
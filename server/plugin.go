@@ -2,7 +2,7 @@ package main
 
 import (
 	"embed"
-	"math/rand"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -10,6 +10,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/larkox/mattermost-plugin-badges/badgesmodel"
 	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
 	"github.com/pkg/errors"
@@ -18,6 +19,11 @@ import (
 //go:embed static
 var staticAssets embed.FS //nolint: gochecknoglobals
 
+var (
+	ErrChannelTypeDisabled = errors.New("games are disabled in this type of channel")
+	ErrTooManyGames        = errors.New("too many unfinished games")
+)
+
 // Plugin implements the interface expected by the Mattermost server to communicate between the server and plugin processes.
 type Plugin struct {
 	plugin.MattermostPlugin
@@ -29,10 +35,22 @@ type Plugin struct {
 	// setConfiguration for usage.
 	configuration *configuration
 
-	router    *mux.Router
-	mm        *pluginapi.Client
-	badgesMap map[string]badgesmodel.BadgeID
-	BotUserID string
+	router *mux.Router
+	mm     *pluginapi.Client
+	// badgesLock synchronizes access to badgesMap, which is replaced when the
+	// configuration changes while badges are being granted.
+	badgesLock sync.RWMutex
+	badgesMap  map[string]badgesmodel.BadgeID
+	BotUserID  string
+	timeoutJob *cluster.Job
+	// botTurns holds the games in which the bot is playing its turn.
+	botTurns sync.Map
+
+	// sprites holds the card images used to draw boards, loaded once by
+	// getSpriteSheet.
+	spritesOnce sync.Once
+	sprites     *SpriteSheet
+	spritesErr  error
 }
 
 // ServeHTTP demonstrates a plugin that handles HTTP requests by greeting the world.
@@ -43,25 +61,507 @@ func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Req
 	p.router.ServeHTTP(w, r)
 }
 
-// See https://developers.mattermost.com/extend/plugins/server/reference/
-func (p *Plugin) NewGame(user1, user2, gID string) (*Game, error) {
-	values := append(GetCardPool(), GetCardPool()...)
-	users := []string{user1, user2}
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(values), func(i, j int) { values[i], values[j] = values[j], values[i] })
-	rand.Shuffle(len(users), func(i, j int) { users[i], users[j] = users[j], users[i] })
+// NewGame creates a game in channelID that has not started yet. The first
+// player is the owner of the game.
+func (p *Plugin) NewGame(players []string, channelID, difficulty string) (*Game, error) {
+	seed, err := newSeed()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot draw seed")
+	}
+
+	return p.NewSeededGame(players, channelID, difficulty, seed)
+}
+
+// NewSeededGame creates a game like NewGame, with the cards laid out from
+// seed. Games created with the same seed and difficulty share their layout.
+func (p *Plugin) NewSeededGame(players []string, channelID, difficulty string, seed int64) (*Game, error) {
+	size, ok := GetBoardSize(difficulty)
+	if !ok {
+		return nil, errors.Errorf("unknown difficulty %q", difficulty)
+	}
+
+	if len(players) == 0 || len(players) > MaxPlayers {
+		return nil, errors.Errorf("cannot play with %d players", len(players))
+	}
+
+	values, err := DealCards(size, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := map[string]int{}
+	for _, player := range players {
+		scores[player] = 0
+	}
 
+	now := model.GetMillis()
 	return &Game{
-		GID:           gID,
-		CardValues:    values,
-		CardFlipped:   []bool{false, false, false, false, false, false, false, false, false, false, false, false},
-		LastFlipped:   -1,
-		CurrentPlayer: users[0],
-		OtherPlayer:   users[1],
-		Scores:        map[string]int{user1: 0, user2: 0},
+		GID:         model.NewId(),
+		ChannelID:   channelID,
+		CardValues:  values,
+		CardFlipped: make([]bool, len(values)),
+		Rows:        size.Rows,
+		Columns:     size.Columns,
+		LastFlipped: -1,
+		Owner:       players[0],
+		Players:     append([]string{}, players...),
+		Scores:      scores,
+		CreateAt:    now,
+		ActiveAt:    now,
+		Seed:        seed,
+		SeedHash:    HashSeed(seed),
+
+		PassTurnOnMatch: !p.getConfiguration().MatchGrantsExtraTurn,
 	}, nil
 }
 
+// startGame creates and stores a new game in channel c. Games in direct
+// messages start right away between both users, once the invitation sent by
+// inviteToGame is accepted. Games in any other channel wait for other members
+// to join until the owner begins them.
+func (p *Plugin) startGame(actingUserID string, c *model.Channel, difficulty string) (*Game, error) {
+	config := p.getConfiguration()
+	if !config.IsChannelTypeEnabled(c.Type) {
+		return nil, ErrChannelTypeDisabled
+	}
+
+	if difficulty == "" {
+		difficulty = config.DefaultDifficulty
+	}
+
+	players := []string{actingUserID}
+	if c.Type == model.CHANNEL_DIRECT {
+		players = append(players, c.GetOtherUserIdForDM(actingUserID))
+	}
+
+	for _, player := range players {
+		if player == p.BotUserID {
+			continue
+		}
+		err := p.checkGameLimit(player)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	game, err := p.NewGame(players, c.Id, difficulty)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create game")
+	}
+
+	if containsString(players, p.BotUserID) {
+		game.BotMemory = config.BotMemory
+		if _, ok := GetBotSkill(game.BotMemory); !ok || game.BotMemory == "" {
+			game.BotMemory = BotMemoryMedium
+		}
+	}
+
+	if c.Type == model.CHANNEL_DIRECT {
+		err = game.Begin(actingUserID)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot begin game")
+		}
+	}
+
+	err = p.addGame(game)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Type == model.CHANNEL_DIRECT {
+		p.startBotTurn(game)
+		return game, nil
+	}
+
+	username := p.getUsername(actingUserID)
+
+	_ = p.mm.Post.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: c.Id,
+		Message: fmt.Sprintf("@%s wants to play memory with up to %d players. Join with `/memory join %s`. @%s can begin the game with `/memory begin %s` once someone joins.",
+			username, MaxPlayers, game.GID, username, game.GID),
+	})
+
+	return game, nil
+}
+
+// addGame stores a new game and adds it to the channel and active game
+// indexes. The board of the game is posted first when board posts are
+// enabled.
+func (p *Plugin) addGame(game *Game) error {
+	if p.getConfiguration().EnableBoardPosts {
+		p.createBoardPost(game)
+	}
+
+	err := p.setGame(game)
+	if err != nil {
+		p.discardGame(game)
+		return errors.Wrap(err, "cannot set game")
+	}
+
+	err = p.addChannelGame(game.ChannelID, game.GID)
+	if err != nil {
+		p.discardGame(game)
+		return errors.Wrap(err, "cannot add game to channel")
+	}
+
+	err = p.addActiveGame(game.GID)
+	if err != nil {
+		p.discardGame(game)
+		return errors.Wrap(err, "cannot add game to the active games")
+	}
+
+	return nil
+}
+
+// discardGame removes a game that was added but is not going to be played,
+// with its board post.
+func (p *Plugin) discardGame(game *Game) {
+	_ = p.removeGame(game)
+
+	if game.BoardPostID != "" {
+		err := p.mm.Post.DeletePost(game.BoardPostID)
+		if err != nil {
+			p.mm.Log.Warn("Cannot delete board post", "gameID", game.GID, "err", err.Error())
+		}
+	}
+}
+
+// finishGame records the result of a game, grants the related badges, posts
+// its summary and moves the game from the active games to the history.
+func (p *Plugin) finishGame(game *Game) {
+	err := p.archiveGame(game)
+	if err != nil {
+		p.mm.Log.Warn("Cannot archive game", "gameID", game.GID, "err", err.Error())
+	}
+
+	switch {
+	case game.Daily != "":
+		p.recordDailyGameResult(game)
+	case game.Solo:
+		p.recordSoloGameResult(game)
+	case game.BotMemory != "":
+		p.recordBotGameResults(game)
+	default:
+		p.recordGameResults(game)
+	}
+
+	if game.SeriesID != "" {
+		series, err := p.updateSeries(game.SeriesID, func(series *Series) {
+			series.AddGame(game)
+		})
+		if err != nil {
+			p.mm.Log.Warn("Cannot update series", "seriesID", game.SeriesID, "err", err.Error())
+		} else {
+			for _, player := range game.Participants() {
+				p.notify(player, fmt.Sprintf("Series score: %s. Play again with `/memory rematch %s`.", p.formatSeries(series), game.GID))
+			}
+		}
+	}
+
+	p.postGameSummary(game)
+
+	_ = p.removeGame(game)
+}
+
+// recordGameResults updates the stats, the leaderboards and the ratings with
+// the result of a game between users, and grants the related badges.
+func (p *Plugin) recordGameResults(game *Game) {
+	results := map[string]string{}
+	for _, player := range game.Participants() {
+		results[player] = game.Result(player)
+		stats, err := p.updatePlayerStats(player, func(stats *PlayerStats) {
+			stats.RecordGame(game, player)
+		})
+		if err != nil {
+			p.mm.Log.Warn("Cannot update stats", "userID", player, "err", err.Error())
+		} else if results[player] == GameResultWin {
+			if stats.Wins >= 1 {
+				p.GrantBadge(AchievementNameWinOne, player)
+			}
+			if stats.Wins >= 5 {
+				p.GrantBadge(AchievementNameWinFive, player)
+			}
+			if stats.Wins >= 10 {
+				p.GrantBadge(AchievementNameWinTen, player)
+			}
+		}
+		p.GrantBadge(AchievementNamePlayOnce, player)
+	}
+
+	err := p.updateLeaderboards(results, time.Now())
+	if err != nil {
+		p.mm.Log.Warn("Cannot update leaderboards", "gameID", game.GID, "err", err.Error())
+	}
+
+	err = p.updateRatings(game)
+	if err != nil {
+		p.mm.Log.Warn("Cannot update ratings", "gameID", game.GID, "err", err.Error())
+	}
+}
+
+// recordBotGameResults updates the stats against the bot of the users that
+// played game. Games against the bot do not count towards the leaderboards or
+// the badges.
+func (p *Plugin) recordBotGameResults(game *Game) {
+	for _, player := range game.Participants() {
+		if player == p.BotUserID {
+			continue
+		}
+		err := p.updateBotStats(player, func(stats *PlayerStats) {
+			stats.RecordGame(game, player)
+		})
+		if err != nil {
+			p.mm.Log.Warn("Cannot update stats against the bot", "userID", player, "err", err.Error())
+		}
+	}
+}
+
+// recordSoloGameResult updates the personal bests and the solo leaderboard
+// with the result of a practice game.
+func (p *Plugin) recordSoloGameResult(game *Game) {
+	best, err := p.recordSoloResult(game)
+	if err != nil {
+		p.mm.Log.Warn("Cannot record solo result", "gameID", game.GID, "err", err.Error())
+		return
+	}
+
+	if best {
+		result := game.SoloResult()
+		p.notify(game.Players[0], fmt.Sprintf("New personal best: you cleared the board in %d moves and %s.", result.Moves, formatDuration(result.Duration)))
+	}
+}
+
+// recordDailyGameResult ranks the result of a daily challenge game, and
+// grants the badges of the streak of its player.
+func (p *Plugin) recordDailyGameResult(game *Game) {
+	challenge, streak, err := p.recordDailyResult(game)
+	if err != nil {
+		p.mm.Log.Warn("Cannot record daily challenge result", "gameID", game.GID, "err", err.Error())
+		return
+	}
+	if challenge == nil {
+		return
+	}
+
+	userID := game.Players[0]
+	if streak.Current >= 3 {
+		p.GrantBadge(AchievementNameDaily, userID)
+	}
+	if streak.Current >= 7 {
+		p.GrantBadge(AchievementNameWeekly, userID)
+	}
+	if streak.Current >= 30 {
+		p.GrantBadge(AchievementNameMonthly, userID)
+	}
+
+	result := challenge.Results[userID]
+	p.notify(userID, fmt.Sprintf("You cleared the daily challenge in %d moves and %s, and are ranked #%d of %d so far. Daily streak: %d.",
+		result.Moves, formatDuration(result.Duration), challenge.Results.RankOf(userID), len(challenge.Results), streak.Current))
+}
+
+// flipCard flips the card at index of game gID on behalf of userID, if the
+// game is still at move seq, and returns the updated game and the value of
+// the card.
+func (p *Plugin) flipCard(gID, userID string, index, seq int) (*Game, string, error) {
+	var value string
+	game, err := p.updateGame(gID, func(game *Game) error {
+		if game.Seq != seq {
+			return ErrStaleMove
+		}
+
+		var flipErr error
+		value, flipErr = game.Flip(userID, index)
+		return flipErr
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if game.Streak >= 4 && game.BotMemory == "" {
+		p.GrantBadge(AchievementNameStreak, userID)
+	}
+
+	if game.Over {
+		p.finishGame(game)
+	}
+
+	return game, value, nil
+}
+
+// resignGame makes userID give up the game. The game finishes once a single
+// player is left.
+func (p *Plugin) resignGame(gID, userID string) (*Game, error) {
+	game, err := p.updateGame(gID, func(game *Game) error {
+		return game.Resign(userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if game.Over {
+		p.finishGame(game)
+	}
+
+	username := p.getUsername(userID)
+	for _, player := range game.Players {
+		message := fmt.Sprintf("@%s resigned the memory game in ~%s.", username, p.getChannelName(game.ChannelID))
+		switch {
+		case game.Over && game.Drawn:
+			message += " The game is a draw."
+		case game.Over && game.Winner() == player:
+			message += " You win!"
+		}
+		p.notify(player, message)
+	}
+
+	return game, nil
+}
+
+// offerDraw makes userID propose the other players to end the game in a
+// draw.
+func (p *Plugin) offerDraw(gID, userID string) (*Game, error) {
+	game, err := p.updateGame(gID, func(game *Game) error {
+		return game.OfferDraw(userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	username := p.getUsername(userID)
+	for _, player := range game.Players {
+		if player == userID {
+			continue
+		}
+		p.notify(player, fmt.Sprintf("@%s offers a draw in the memory game in ~%s. Accept it there with `/memory draw accept`.", username, p.getChannelName(game.ChannelID)))
+	}
+
+	return game, nil
+}
+
+// acceptDraw makes userID agree to the standing draw offer. The game
+// finishes once every player agreed.
+func (p *Plugin) acceptDraw(gID, userID string) (*Game, error) {
+	game, err := p.updateGame(gID, func(game *Game) error {
+		return game.AcceptDraw(userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if game.Over {
+		p.finishGame(game)
+	}
+
+	username := p.getUsername(userID)
+	for _, player := range game.Players {
+		if player == userID {
+			continue
+		}
+		message := fmt.Sprintf("@%s accepted the draw in the memory game in ~%s.", username, p.getChannelName(game.ChannelID))
+		if game.Over {
+			message += " The game ended in a draw."
+		}
+		p.notify(player, message)
+	}
+
+	return game, nil
+}
+
+// joinGame adds userID to a game that has not started yet.
+func (p *Plugin) joinGame(gID, userID string) (*Game, error) {
+	err := p.checkGameLimit(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.updateGame(gID, func(game *Game) error {
+		return game.Join(userID)
+	})
+}
+
+// leaveGame removes userID from a game that has not started yet. The game is
+// removed once nobody is left.
+func (p *Plugin) leaveGame(gID, userID string) (*Game, error) {
+	game, err := p.updateGame(gID, func(game *Game) error {
+		return game.Leave(userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(game.Players) == 0 {
+		_ = p.removeGame(game)
+	}
+
+	return game, nil
+}
+
+// beginGame starts a game on behalf of its owner.
+func (p *Plugin) beginGame(gID, userID string) (*Game, error) {
+	game, err := p.updateGame(gID, func(game *Game) error {
+		return game.Begin(userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, player := range game.Players {
+		if player == userID {
+			continue
+		}
+		p.notify(player, fmt.Sprintf("The memory game in ~%s has begun. It is @%s's turn.", p.getChannelName(game.ChannelID), p.getUsername(game.CurrentPlayer())))
+	}
+
+	return game, nil
+}
+
+// checkGameLimit returns ErrTooManyGames if userID cannot take part in
+// another game.
+func (p *Plugin) checkGameLimit(userID string) error {
+	limit := p.getConfiguration().MaxGamesPerUser
+	if limit <= 0 {
+		return nil
+	}
+
+	gameIDs, err := p.getActiveGameIDs()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, gID := range gameIDs {
+		game, err := p.getGame(gID)
+		if errors.Is(err, ErrGameNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !game.Over && game.IsPlayer(userID) {
+			count++
+		}
+	}
+
+	if count >= limit {
+		return ErrTooManyGames
+	}
+
+	return nil
+}
+
+// notify sends message to userID from the bot, unless notifications are
+// disabled.
+func (p *Plugin) notify(userID, message string) {
+	if !p.getConfiguration().EnableNotifications || userID == p.BotUserID {
+		return
+	}
+
+	_ = p.mm.Post.DM(p.BotUserID, userID, &model.Post{
+		Message: message,
+	})
+}
+
 func (p *Plugin) OnActivate() error {
 	botID, err := p.Helpers.EnsureBot(&model.Bot{
 		Username:    "memory",
@@ -75,7 +575,27 @@ func (p *Plugin) OnActivate() error {
 
 	p.mm = pluginapi.NewClient(p.API)
 	p.initializeAPI(staticAssets)
-	p.EnsureBadges()
+	if p.getConfiguration().EnableBadges {
+		p.EnsureBadges()
+	}
+
+	err = p.mm.SlashCommand.Register(createMemoryCommand())
+	if err != nil {
+		return errors.Wrap(err, "failed to register command")
+	}
+
+	err = p.scheduleTimeoutJob()
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule turn timeout job")
+	}
+
+	return nil
+}
+
+func (p *Plugin) OnDeactivate() error {
+	if p.timeoutJob != nil {
+		return p.timeoutJob.Close()
+	}
 
 	return nil
 }
@@ -6,7 +6,9 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestServeHTTP(t *testing.T) {
@@ -26,3 +28,28 @@ func TestServeHTTP(t *testing.T) {
 
 	assert.Equal("Hello, world!", bodyString)
 }
+
+func TestStartGameConfiguration(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{
+		DefaultDifficulty:    DifficultyMedium,
+		MaxGamesPerUser:      1,
+		EnablePublicChannels: true,
+	})
+
+	_, err := p.startGame("user1", &model.Channel{Id: "dm", Type: model.CHANNEL_DIRECT, Name: "user1__user2"}, "")
+	assert.ErrorIs(t, err, ErrChannelTypeDisabled)
+
+	game, err := p.startGame("user1", &model.Channel{Id: "channel", Type: model.CHANNEL_OPEN}, "")
+	require.NoError(t, err)
+	assert.Equal(t, 5, game.Rows)
+	assert.True(t, game.PassTurnOnMatch)
+
+	_, err = p.startGame("user1", &model.Channel{Id: "channel", Type: model.CHANNEL_OPEN}, "")
+	assert.ErrorIs(t, err, ErrTooManyGames)
+
+	_, err = p.joinGame(game.GID, "user2")
+	require.NoError(t, err)
+	_, err = p.startGame("user2", &model.Channel{Id: "channel", Type: model.CHANNEL_OPEN}, "")
+	assert.ErrorIs(t, err, ErrTooManyGames)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ratingK is the largest rating change of an established player in a
+	// single game, and provisionalRatingK the one of a provisional player.
+	ratingK            = 20
+	provisionalRatingK = 40
+)
+
+// expectedScore returns the expected score of a player rated rating against
+// a player rated opponent, between 0 for a loss and 1 for a win.
+func expectedScore(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/400))
+}
+
+// RatingChanges returns the rating change of every participant of game, by
+// user ID, given their ratings before it. Every pair of participants counts
+// as a match, and the changes are averaged over the opponents so that a game
+// weighs as much whatever its number of players.
+func RatingChanges(game *Game, ratings map[string]*Rating) map[string]float64 {
+	participants := game.Participants()
+	changes := map[string]float64{}
+	if len(participants) < 2 {
+		return changes
+	}
+
+	for _, player := range participants {
+		k := float64(ratingK)
+		if ratings[player].Provisional() {
+			k = provisionalRatingK
+		}
+
+		for _, opponent := range participants {
+			if opponent == player {
+				continue
+			}
+
+			var score float64
+			switch game.HeadToHead(player, opponent) {
+			case GameResultWin:
+				score = 1
+			case GameResultDraw:
+				score = 0.5
+			}
+
+			expected := expectedScore(ratings[player].Rating, ratings[opponent].Rating)
+			changes[player] += k * (score - expected) / float64(len(participants)-1)
+		}
+	}
+
+	return changes
+}
+
+func ratingKey(userID string) string {
+	return KeyPrefixRating + userID
+}
+
+func decodeRating(data []byte) (*Rating, error) {
+	rating := &Rating{Rating: InitialRating, Peak: InitialRating}
+	if len(data) == 0 {
+		return rating, nil
+	}
+
+	err := json.Unmarshal(data, rating)
+	if err != nil {
+		return nil, err
+	}
+
+	return rating, nil
+}
+
+// getRating returns the rating of userID. Users that never finished a rated
+// game have the initial rating.
+func (p *Plugin) getRating(userID string) (*Rating, error) {
+	var data []byte
+	err := p.mm.KV.Get(ratingKey(userID), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRating(data)
+}
+
+// updateRatings changes the ratings of the participants of a finished game
+// between users. Changes are computed from the ratings before the game and
+// applied atomically to each player.
+func (p *Plugin) updateRatings(game *Game) error {
+	ratings := map[string]*Rating{}
+	for _, player := range game.Participants() {
+		rating, err := p.getRating(player)
+		if err != nil {
+			return errors.Wrapf(err, "cannot get rating of %s", player)
+		}
+		ratings[player] = rating
+	}
+
+	for player, delta := range RatingChanges(game, ratings) {
+		err := p.mm.KV.SetAtomicWithRetries(ratingKey(player), func(oldValue []byte) (interface{}, error) {
+			rating, err := decodeRating(oldValue)
+			if err != nil {
+				return nil, err
+			}
+
+			rating.Apply(game.GID, delta, game.EndAt)
+
+			return rating, nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "cannot update rating of %s", player)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatingChanges(t *testing.T) {
+	game := &Game{
+		Players: []string{"user1", "user2"},
+		Scores:  map[string]int{"user1": 3, "user2": 1},
+		Over:    true,
+	}
+
+	established := &Rating{Rating: InitialRating, Games: ProvisionalGames}
+	changes := RatingChanges(game, map[string]*Rating{"user1": established, "user2": established})
+	assert.InDelta(t, ratingK/2, changes["user1"], 0.001)
+	assert.InDelta(t, -ratingK/2, changes["user2"], 0.001)
+
+	provisional := &Rating{Rating: InitialRating}
+	changes = RatingChanges(game, map[string]*Rating{"user1": provisional, "user2": established})
+	assert.InDelta(t, provisionalRatingK/2, changes["user1"], 0.001)
+	assert.InDelta(t, -ratingK/2, changes["user2"], 0.001)
+
+	game.Scores["user2"] = 3
+	game.Drawn = true
+	changes = RatingChanges(game, map[string]*Rating{"user1": established, "user2": established})
+	assert.InDelta(t, 0, changes["user1"], 0.001)
+	assert.InDelta(t, 0, changes["user2"], 0.001)
+}
+
+func TestRatingApply(t *testing.T) {
+	rating := &Rating{Rating: InitialRating, Peak: InitialRating}
+	rating.Apply("game1", 10, 1)
+	rating.Apply("game1", 10, 1)
+	rating.Apply("game2", -20, 2)
+
+	assert.Equal(t, float64(InitialRating-10), rating.Rating)
+	assert.Equal(t, float64(InitialRating+10), rating.Peak)
+	assert.Equal(t, 2, rating.Games)
+	require.Len(t, rating.History, 2)
+	assert.Equal(t, RatingChange{GID: "game2", Rating: InitialRating - 10, Delta: -20, At: 2}, rating.History[1])
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrGameNotFinished = errors.New("game is not finished")
+	ErrRematchPlayed   = errors.New("rematch already played")
+)
+
+// errRematchClaimed aborts claiming the rematch of a game that already has
+// one.
+var errRematchClaimed = errors.New("rematch already claimed")
+
+// rematchGame starts a new game with the participants and settings of the
+// finished game gID, on behalf of userID. The player that moved second moves
+// first this time, and the result counts towards the series of the games.
+// Asking for the rematch of a game twice returns the same rematch.
+func (p *Plugin) rematchGame(gID, userID string) (*Game, *Series, error) {
+	previous, err := p.getArchivedGame(gID)
+	if errors.Is(err, ErrGameNotFound) {
+		if _, err = p.getGame(gID); err == nil {
+			return nil, nil, ErrGameNotFinished
+		}
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !containsString(previous.Participants(), userID) {
+		return nil, nil, ErrNotAPlayer
+	}
+
+	if previous.Solo {
+		return nil, nil, ErrNotEnoughPlayers
+	}
+
+	if previous.RematchID != "" {
+		return p.getRematch(previous)
+	}
+
+	c, err := p.mm.Channel.Get(previous.ChannelID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot get channel")
+	}
+	if !p.getConfiguration().IsChannelTypeEnabled(c.Type) {
+		return nil, nil, ErrChannelTypeDisabled
+	}
+
+	order := previous.RematchOrder()
+	for _, player := range order {
+		if player == p.BotUserID {
+			continue
+		}
+		err = p.checkGameLimit(player)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	difficulty, ok := GetDifficulty(previous.Rows, previous.Columns)
+	if !ok {
+		return nil, nil, errors.Errorf("unknown board of %dx%d cards", previous.Rows, previous.Columns)
+	}
+
+	game, err := p.NewGame(order, previous.ChannelID, difficulty)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot create game")
+	}
+	game.Owner = userID
+	game.PassTurnOnMatch = previous.PassTurnOnMatch
+	game.BotMemory = previous.BotMemory
+	game.SeriesID = previous.SeriesID
+	if game.SeriesID == "" {
+		game.SeriesID = previous.GID
+	}
+	game.start()
+
+	// Store the game before claiming the rematch, so that players asking for
+	// it at the same time end up in the same game. The games of the players
+	// that lose the claim are discarded.
+	err = p.addGame(game)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = p.mm.KV.SetAtomicWithRetries(historyGameKey(gID), func(oldValue []byte) (interface{}, error) {
+		stored, err := decodeGame(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		if stored.RematchID != "" {
+			previous = stored
+			return nil, errRematchClaimed
+		}
+
+		stored.RematchID = game.GID
+		return stored, nil
+	})
+	if err != nil {
+		p.discardGame(game)
+		if errors.Is(err, errRematchClaimed) {
+			return p.getRematch(previous)
+		}
+		return nil, nil, errors.Wrap(err, "cannot claim rematch")
+	}
+
+	// The first game of a series only joins it once it is rematched.
+	var series *Series
+	if previous.SeriesID == "" {
+		series, err = p.updateSeries(game.SeriesID, func(series *Series) {
+			series.AddGame(previous)
+		})
+	} else {
+		series, err = p.getSeries(game.SeriesID)
+	}
+	if err != nil {
+		p.releaseRematch(gID, game.GID)
+		p.discardGame(game)
+		return nil, nil, errors.Wrap(err, "cannot get series")
+	}
+
+	username := p.getUsername(userID)
+	for _, player := range game.Players {
+		p.mm.Frontend.PublishWebSocketEvent("rematch", map[string]interface{}{
+			"gID":         game.GID,
+			"channelID":   game.ChannelID,
+			"previousGID": previous.GID,
+		}, &model.WebsocketBroadcast{UserId: player})
+
+		if player != userID {
+			p.notify(player, fmt.Sprintf("@%s started a rematch of your memory game in ~%s. Series score: %s.", username, p.getChannelName(game.ChannelID), p.formatSeries(series)))
+		}
+	}
+	p.startBotTurn(game)
+
+	return game, series, nil
+}
+
+// releaseRematch gives up the claim of rematchID on the finished game gID, so
+// that the rematch can be asked for again.
+func (p *Plugin) releaseRematch(gID, rematchID string) {
+	err := p.mm.KV.SetAtomicWithRetries(historyGameKey(gID), func(oldValue []byte) (interface{}, error) {
+		stored, err := decodeGame(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		if stored.RematchID == rematchID {
+			stored.RematchID = ""
+		}
+		return stored, nil
+	})
+	if err != nil {
+		p.mm.Log.Warn("Cannot release rematch", "gameID", gID, "err", err.Error())
+	}
+}
+
+// getRematch returns the rematch already started for previous, as long as it
+// is not finished yet.
+func (p *Plugin) getRematch(previous *Game) (*Game, *Series, error) {
+	game, err := p.getGame(previous.RematchID)
+	if errors.Is(err, ErrGameNotFound) {
+		return nil, nil, ErrRematchPlayed
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	series, err := p.getSeries(game.SeriesID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot get series")
+	}
+
+	return game, series, nil
+}
+
+// formatSeries describes the score of series, such as "@alice 2 - @bob 1, 1
+// draw".
+func (p *Plugin) formatSeries(series *Series) string {
+	scores := []string{}
+	for _, player := range series.Players {
+		scores = append(scores, fmt.Sprintf("@%s %d", p.getUsername(player), series.Wins[player]))
+	}
+
+	message := strings.Join(scores, " - ")
+	switch series.Draws {
+	case 0:
+	case 1:
+		message += ", 1 draw"
+	default:
+		message += fmt.Sprintf(", %d draws", series.Draws)
+	}
+
+	return message
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postRematch(t *testing.T, p *Plugin, userID, gameID string) (int, *RematchResponse) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/game/"+gameID+"/rematch", nil)
+	r.Header.Set("Mattermost-User-ID", userID)
+
+	p.router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+
+	resp := &RematchResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(resp))
+
+	return w.Code, resp
+}
+
+func TestRematch(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableDirectMessages: true, MatchGrantsExtraTurn: true})
+	game := newTestGame(t, p)
+	game.ChannelID = "user1__user2"
+	game.Rows, game.Columns = 4, 3
+	require.NoError(t, p.setGame(game))
+
+	code, _ := postRematch(t, p, "user2", game.GID)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	_, err := p.resignGame(game.GID, "user2")
+	require.NoError(t, err)
+
+	code, _ = postRematch(t, p, "user3", game.GID)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, resp := postRematch(t, p, "user2", game.GID)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Turn)
+	assert.Equal(t, game.GID, resp.Series.ID)
+	assert.Equal(t, 1, resp.Series.Games)
+	assert.Equal(t, 1, resp.Series.Wins["user1"])
+
+	rematch, err := p.getGame(resp.GID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user2", "user1"}, rematch.Players)
+	assert.Equal(t, 12, len(rematch.CardValues))
+	assert.False(t, rematch.PassTurnOnMatch)
+	assert.Equal(t, game.GID, rematch.SeriesID)
+
+	code, again := postRematch(t, p, "user1", game.GID)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, resp.GID, again.GID)
+	assert.False(t, again.Turn)
+
+	_, err = p.resignGame(rematch.GID, "user1")
+	require.NoError(t, err)
+	series, err := p.getSeries(game.GID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, series.Games)
+	assert.Equal(t, map[string]int{"user1": 1, "user2": 1}, series.Wins)
+
+	code, _ = postRematch(t, p, "user1", game.GID)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp = postRematch(t, p, "user1", rematch.GID)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Turn)
+	assert.Equal(t, 2, resp.Series.Games)
+}
+
+func TestRematchConcurrent(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableDirectMessages: true, EnableBoardPosts: true})
+	api := p.API.(*fakeAPI)
+	game := newTestGame(t, p)
+	game.ChannelID = "user1__user2"
+	game.Rows, game.Columns = 4, 3
+	require.NoError(t, p.setGame(game))
+
+	_, err := p.resignGame(game.GID, "user2")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	rematches := make([]*Game, 2)
+	errs := make([]error, 2)
+	for i, player := range []string{"user1", "user2"} {
+		wg.Add(1)
+		go func(i int, player string) {
+			defer wg.Done()
+			rematches[i], _, errs[i] = p.rematchGame(game.GID, player)
+		}(i, player)
+	}
+	wg.Wait()
+
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	assert.Equal(t, rematches[0].GID, rematches[1].GID)
+
+	gameIDs, err := p.getActiveGameIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{rematches[0].GID}, gameIDs)
+	assert.Len(t, api.posts, 1)
+
+	series, err := p.getSeries(game.GID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, series.Games)
+}
+
+func TestRematchAddGameFailure(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableDirectMessages: true})
+	api := p.API.(*fakeAPI)
+	game := newTestGame(t, p)
+	game.ChannelID = "user1__user2"
+	game.Rows, game.Columns = 4, 3
+	require.NoError(t, p.setGame(game))
+
+	_, err := p.resignGame(game.GID, "user2")
+	require.NoError(t, err)
+
+	api.failKey = channelGamesKey(game.ChannelID)
+	_, _, err = p.rematchGame(game.GID, "user1")
+	require.Error(t, err)
+
+	previous, err := p.getArchivedGame(game.GID)
+	require.NoError(t, err)
+	assert.Empty(t, previous.RematchID)
+	gameIDs, err := p.getActiveGameIDs()
+	require.NoError(t, err)
+	assert.Empty(t, gameIDs)
+
+	api.failKey = ""
+	rematch, _, err := p.rematchGame(game.GID, "user1")
+	require.NoError(t, err)
+	again, _, err := p.rematchGame(game.GID, "user2")
+	require.NoError(t, err)
+	assert.Equal(t, rematch.GID, again.GID)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cardRanks maps the ranks of the card values to their names.
+var cardRanks = map[string]string{ //nolint: gochecknoglobals
+	"Ace":   "ace",
+	"King":  "king",
+	"Queen": "queen",
+	"Jack":  "jack",
+}
+
+// CardName returns the name of a card value as read aloud, like ace of
+// hearts for heartsAce.
+func CardName(value string) string {
+	if value == "joker" {
+		return "joker"
+	}
+
+	for _, suit := range cardSuits {
+		if !strings.HasPrefix(value, suit.name) {
+			continue
+		}
+
+		rank := strings.TrimPrefix(value, suit.name)
+		if name, ok := cardRanks[rank]; ok {
+			rank = name
+		}
+		return rank + " of " + suit.name
+	}
+
+	return value
+}
+
+// renderBoard renders the board of game in format, one of the BoardFormat
+// constants. It returns false if the format is unknown.
+func (p *Plugin) renderBoard(game *Game, format string) (string, bool) {
+	switch format {
+	case BoardFormatMarkdown, "":
+		return p.markdownBoard(game), true
+	case BoardFormatText:
+		return p.textBoard(game), true
+	default:
+		return "", false
+	}
+}
+
+// markdownBoard renders game as a Markdown table with the column letters and
+// row numbers used to name the cards. Hidden cards are shown as ?, and the
+// card turned over in the current turn in bold.
+func (p *Plugin) markdownBoard(game *Game) string {
+	text := p.describeStatus(game) + "\n\n|   |"
+	for column := 0; column < game.Columns; column++ {
+		text += fmt.Sprintf(" %c |", 'a'+column)
+	}
+	text += "\n|:-:|" + strings.Repeat(":-:|", game.Columns) + "\n"
+
+	for row := 0; row < game.Rows; row++ {
+		text += fmt.Sprintf("| %d |", row+1)
+		for column := 0; column < game.Columns; column++ {
+			index := row*game.Columns + column
+			switch {
+			case index == game.LastFlipped:
+				text += fmt.Sprintf(" **%s** |", CardLabel(game.CardValues[index]))
+			case game.CardFlipped[index]:
+				text += fmt.Sprintf(" %s |", CardLabel(game.CardValues[index]))
+			default:
+				text += " ? |"
+			}
+		}
+		text += "\n"
+	}
+
+	return text + "\n" + p.describeScores(game)
+}
+
+// textBoard renders game as plain sentences that screen readers can follow,
+// one per row of cards.
+func (p *Plugin) textBoard(game *Game) string {
+	text := p.describeStatus(game) + "\n"
+	text += fmt.Sprintf("The board has %d rows of %d cards, and %d pairs left to find.\n", game.Rows, game.Columns, countHiddenCards(game)/2)
+
+	for row := 0; row < game.Rows; row++ {
+		cards := []string{}
+		for column := 0; column < game.Columns; column++ {
+			index := row*game.Columns + column
+			switch {
+			case index == game.LastFlipped:
+				cards = append(cards, fmt.Sprintf("%s %s, turned over", cardCoordinates(game, index), CardName(game.CardValues[index])))
+			case game.CardFlipped[index]:
+				cards = append(cards, fmt.Sprintf("%s %s, matched", cardCoordinates(game, index), CardName(game.CardValues[index])))
+			default:
+				cards = append(cards, fmt.Sprintf("%s hidden", cardCoordinates(game, index)))
+			}
+		}
+		text += fmt.Sprintf("Row %d: %s.\n", row+1, strings.Join(cards, "; "))
+	}
+
+	for _, player := range game.Players {
+		text += fmt.Sprintf("@%s has %d pairs.\n", p.getUsername(player), game.Scores[player])
+	}
+	for _, player := range game.Resignations {
+		text += fmt.Sprintf("@%s resigned with %d pairs.\n", p.getUsername(player), game.Scores[player])
+	}
+
+	return text
+}
+
+// countHiddenCards returns the number of cards of game that are face down.
+func countHiddenCards(game *Game) int {
+	hidden := 0
+	for _, flipped := range game.CardFlipped {
+		if !flipped {
+			hidden++
+		}
+	}
+
+	return hidden
+}
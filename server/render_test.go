@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardName(t *testing.T) {
+	assert.Equal(t, "ace of hearts", CardName("heartsAce"))
+	assert.Equal(t, "10 of hearts", CardName("hearts10"))
+	assert.Equal(t, "joker", CardName("joker"))
+}
+
+func TestRenderBoard(t *testing.T) {
+	p := newTestPlugin(t)
+	game := newTestGame(t, p)
+	game.CardFlipped = []bool{true, true, true, false}
+	game.LastFlipped = 2
+
+	board, ok := p.renderBoard(game, BoardFormatMarkdown)
+	require.True(t, ok)
+	assert.Equal(t, "It is @user1's turn.\n\n"+
+		"|   | a | b |\n"+
+		"|:-:|:-:|:-:|\n"+
+		"| 1 | Joker | Joker |\n"+
+		"| 2 | **A♥** | ? |\n"+
+		"\n"+
+		"* @user1: 0 pairs\n"+
+		"* @user2: 0 pairs\n", board)
+
+	board, ok = p.renderBoard(game, BoardFormatText)
+	require.True(t, ok)
+	assert.Contains(t, board, "Row 1: a1 joker, matched; b1 joker, matched.\n")
+	assert.Contains(t, board, "Row 2: a2 ace of hearts, turned over; b2 hidden.\n")
+	assert.Contains(t, board, "and 0 pairs left to find.\n")
+
+	_, ok = p.renderBoard(game, "html")
+	assert.False(t, ok)
+}
+
+func TestGetBoard(t *testing.T) {
+	p := newTestPlugin(t)
+	newTestGame(t, p)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/game/game/board?format=text", nil)
+	r.Header.Set("Mattermost-User-ID", "user1")
+	p.router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	resp := BoardResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, BoardFormatText, resp.Format)
+	assert.Contains(t, resp.Board, "Row 1: a1 hidden; b1 hidden.\n")
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/game/game/board?format=html", nil)
+	r.Header.Set("Mattermost-User-ID", "user1")
+	p.router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ErrorIDInvalidParameter, decodeAPIError(t, w).ID)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// resultTotals returns the moves made in game by all of its players, and the
+// longest streak of matches with the player who made it.
+func resultTotals(game *Game) (totalMoves, longestStreak int, streakUserID string) {
+	moves, streaks := game.PlayerMoves()
+	for _, player := range game.Participants() {
+		totalMoves += moves[player]
+		if streaks[player] > longestStreak {
+			longestStreak = streaks[player]
+			streakUserID = player
+		}
+	}
+
+	return totalMoves, longestStreak, streakUserID
+}
+
+// resultProps returns the props of the result post of the finished game, for
+// the webapp to render it. Props only hold maps, slices and basic types so
+// that they go through the plugin RPC.
+func (p *Plugin) resultProps(game *Game) map[string]interface{} {
+	moves, streaks := game.PlayerMoves()
+	scores := []interface{}{}
+	for _, player := range game.Participants() {
+		scores = append(scores, map[string]interface{}{
+			"userID":   player,
+			"username": p.getUsername(player),
+			"pairs":    game.Scores[player],
+			"moves":    moves[player],
+			"streak":   streaks[player],
+			"resigned": !game.IsPlayer(player),
+		})
+	}
+
+	totalMoves, longestStreak, streakUserID := resultTotals(game)
+
+	return map[string]interface{}{
+		"gID":                 game.GID,
+		"winner":              game.Winner(),
+		"drawn":               game.Drawn,
+		"solo":                game.Solo,
+		"scores":              scores,
+		"moves":               totalMoves,
+		"duration":            game.EndAt - game.CreateAt,
+		"longestStreak":       longestStreak,
+		"longestStreakUserID": streakUserID,
+	}
+}
+
+// describeResult returns the Markdown message of the result post of the
+// finished game, shown by clients that do not render its post type.
+func (p *Plugin) describeResult(game *Game) string {
+	text := "#### Memory game over\n" + p.describeStatus(game) + "\n" + p.describeScores(game)
+
+	totalMoves, longestStreak, streakUserID := resultTotals(game)
+	duration := time.Duration(game.EndAt-game.CreateAt) * time.Millisecond
+	text += fmt.Sprintf("\n%d moves in %s.", totalMoves, duration.Round(time.Second))
+	if longestStreak > 1 {
+		text += fmt.Sprintf(" Longest streak: %d pairs in a row by @%s.", longestStreak, p.getUsername(streakUserID))
+	}
+
+	return text + "\n"
+}
+
+// postGameSummary posts the result of the finished game in its channel, with
+// a snapshot of the final board attached and a button to play again. The
+// boards of daily challenges are not posted, so that they are not given away.
+func (p *Plugin) postGameSummary(game *Game) {
+	if !p.getConfiguration().EnableSummaryPosts || game.Daily != "" {
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: game.ChannelID,
+		Type:      PostTypeResult,
+		Message:   p.describeResult(game),
+	}
+	for key, value := range p.resultProps(game) {
+		post.AddProp(key, value)
+	}
+
+	if !game.Solo {
+		model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+			Actions: []*model.PostAction{{
+				Id:   "rematch",
+				Type: model.POST_ACTION_TYPE_BUTTON,
+				Name: "Rematch",
+				Integration: &model.PostActionIntegration{
+					URL: fmt.Sprintf("/plugins/%s/api/v1/game/%s/rematch/action", manifest.Id, game.GID),
+				},
+			}},
+		}})
+	}
+
+	snapshot, err := p.renderSnapshot(game)
+	if err == nil {
+		var info *model.FileInfo
+		info, err = p.mm.File.Upload(bytes.NewReader(snapshot), fmt.Sprintf("memory-%s.png", game.GID), game.ChannelID)
+		if err == nil {
+			post.FileIds = []string{info.Id}
+		}
+	}
+	if err != nil {
+		p.mm.Log.Warn("Cannot attach snapshot to summary", "gameID", game.GID, "err", err.Error())
+	}
+
+	err = p.mm.Post.CreatePost(post)
+	if err != nil {
+		p.mm.Log.Warn("Cannot post game summary", "gameID", game.GID, "err", err.Error())
+	}
+}
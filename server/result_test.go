@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rematchAction(p *Plugin, userID, gameID string) *model.PostActionIntegrationResponse {
+	req := &model.PostActionIntegrationRequest{UserId: userID}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/game/"+gameID+"/rematch/action", bytes.NewReader(req.ToJson()))
+	r.Header.Set("Mattermost-User-ID", userID)
+
+	p.router.ServeHTTP(w, r)
+
+	return model.PostActionIntegrationResponseFromJson(w.Result().Body)
+}
+
+func TestPostGameSummary(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableSummaryPosts: true})
+	api := p.API.(*fakeAPI)
+	game := newTestGame(t, p)
+	game.ChannelID = "channel"
+
+	require.Equal(t, http.StatusOK, flipCard(p, "user1", "game", 0, 0))
+	require.Equal(t, http.StatusOK, flipCard(p, "user1", "game", 1, 1))
+	require.Equal(t, http.StatusOK, flipCard(p, "user1", "game", 2, 2))
+	require.Equal(t, http.StatusOK, flipCard(p, "user1", "game", 3, 3))
+
+	require.Len(t, api.posts, 1)
+	post := api.posts[0]
+	assert.Equal(t, PostTypeResult, post.Type)
+	assert.Contains(t, post.Message, "@user1 won the game.")
+	assert.Contains(t, post.Message, "2 moves in")
+	assert.Contains(t, post.Message, "Longest streak: 2 pairs in a row by @user1.")
+	require.Len(t, post.FileIds, 1)
+	assert.Contains(t, api.files, post.FileIds[0])
+
+	assert.Equal(t, "game", post.GetProp("gID"))
+	assert.Equal(t, "user1", post.GetProp("winner"))
+	assert.Equal(t, 2, post.GetProp("moves"))
+	assert.Equal(t, 2, post.GetProp("longestStreak"))
+	assert.Equal(t, "user1", post.GetProp("longestStreakUserID"))
+	scores, ok := post.GetProp("scores").([]interface{})
+	require.True(t, ok)
+	require.Len(t, scores, 2)
+	assert.Equal(t, map[string]interface{}{
+		"userID":   "user1",
+		"username": "user1",
+		"pairs":    2,
+		"moves":    2,
+		"streak":   2,
+		"resigned": false,
+	}, scores[0])
+
+	attachments := post.Attachments()
+	require.Len(t, attachments, 1)
+	require.Len(t, attachments[0].Actions, 1)
+	assert.Equal(t, "Rematch", attachments[0].Actions[0].Name)
+
+	assert.Equal(t, "You are not a player of this game.", rematchAction(p, "user3", "game").EphemeralText)
+}
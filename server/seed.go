@@ -0,0 +1,83 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoSeed     = errors.New("game has no seed")
+	ErrSeedHidden = errors.New("seed not revealed yet")
+)
+
+// newSeed returns a random seed that cannot be guessed from the time it was
+// drawn at.
+func newSeed() (int64, error) {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return 0, err
+	}
+
+	return int64(binary.BigEndian.Uint64(b[:]) >> 1), nil
+}
+
+// HashSeed returns the commitment to seed published when a game starts: the
+// hex encoded SHA-256 hash of the seed written in base 10.
+func HashSeed(seed int64) string {
+	hash := sha256.Sum256([]byte(strconv.FormatInt(seed, 10)))
+	return hex.EncodeToString(hash[:])
+}
+
+// DealCards lays out the cards of a board of the given size from seed. The
+// same seed and size always give the same layout.
+func DealCards(size BoardSize, seed int64) ([]string, error) {
+	pool := GetCardPool()
+	if size.Cards()%2 != 0 || size.Cards()/2 > len(pool) {
+		return nil, errors.Errorf("cannot build a board of %d cards", size.Cards())
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	values := append([]string{}, pool[:size.Cards()/2]...)
+	values = append(values, values...)
+	rng.Shuffle(len(values), func(i, j int) { values[i], values[j] = values[j], values[i] })
+
+	return values, nil
+}
+
+// verifyGameSeed checks the layout of the finished game against its revealed
+// seed at time now.
+func verifyGameSeed(game *Game, now time.Time) (*VerifySeedResponse, error) {
+	if game.SeedHash == "" {
+		return nil, ErrNoSeed
+	}
+	if !game.SeedRevealed(now) {
+		return nil, ErrSeedHidden
+	}
+
+	cards, err := DealCards(BoardSize{Rows: game.Rows, Columns: game.Columns}, game.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	cardsMatch := len(cards) == len(game.CardValues)
+	for i := 0; cardsMatch && i < len(cards); i++ {
+		cardsMatch = cards[i] == game.CardValues[i]
+	}
+
+	return &VerifySeedResponse{
+		GID:        game.GID,
+		Seed:       strconv.FormatInt(game.Seed, 10),
+		SeedHash:   game.SeedHash,
+		HashMatch:  HashSeed(game.Seed) == game.SeedHash,
+		Cards:      cards,
+		CardsMatch: cardsMatch,
+	}, nil
+}
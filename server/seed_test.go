@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDealCards(t *testing.T) {
+	size, _ := GetBoardSize(DifficultyHard)
+
+	cards, err := DealCards(size, 42)
+	require.NoError(t, err)
+	assert.Len(t, cards, size.Cards())
+
+	again, err := DealCards(size, 42)
+	require.NoError(t, err)
+	assert.Equal(t, cards, again)
+
+	other, err := DealCards(size, 43)
+	require.NoError(t, err)
+	assert.NotEqual(t, cards, other)
+}
+
+func TestVerifyGameSeed(t *testing.T) {
+	p := newTestPlugin(t)
+	now := time.Date(2021, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+	game, err := p.NewGame([]string{"user1"}, "channel", DifficultyEasy)
+	require.NoError(t, err)
+	assert.Equal(t, HashSeed(game.Seed), game.SeedHash)
+
+	_, err = verifyGameSeed(game, now)
+	assert.ErrorIs(t, err, ErrSeedHidden)
+
+	game.Over = true
+	resp, err := verifyGameSeed(game, now)
+	require.NoError(t, err)
+	assert.True(t, resp.HashMatch)
+	assert.True(t, resp.CardsMatch)
+	assert.Equal(t, game.CardValues, resp.Cards)
+
+	game.CardValues[0], game.CardValues[1] = game.CardValues[1], game.CardValues[0]
+	resp, err = verifyGameSeed(game, now)
+	require.NoError(t, err)
+	assert.Equal(t, game.CardValues[0] == game.CardValues[1], resp.CardsMatch)
+
+	game.Daily = "2021-03-04"
+	_, err = verifyGameSeed(game, now)
+	assert.ErrorIs(t, err, ErrSeedHidden)
+	_, err = verifyGameSeed(game, now.Add(24*time.Hour))
+	assert.NoError(t, err)
+
+	_, err = verifyGameSeed(&Game{Over: true}, now)
+	assert.ErrorIs(t, err, ErrNoSeed)
+}
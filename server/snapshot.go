@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// Cards are drawn at half the size of the sprites.
+	snapshotCardWidth  = 70
+	snapshotCardHeight = 95
+	snapshotGap        = 8
+	snapshotMargin     = 16
+	snapshotLineHeight = 18
+)
+
+var (
+	snapshotBackground = image.NewUniform(color.RGBA{R: 0x35, G: 0x65, B: 0x4d, A: 0xff}) //nolint: gochecknoglobals
+	snapshotText       = image.NewUniform(color.White)                                    //nolint: gochecknoglobals
+)
+
+// SpriteSheet holds the card sprites of static/cards.png, named and placed by
+// the frames of static/cards.json.
+type SpriteSheet struct {
+	image  image.Image
+	frames map[string]image.Rectangle
+}
+
+// LoadSpriteSheet reads the card sprites from the static assets.
+func LoadSpriteSheet(assets fs.FS) (*SpriteSheet, error) {
+	data, err := fs.ReadFile(assets, "static/cards.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read sprite metadata")
+	}
+
+	var metadata struct {
+		Frames []struct {
+			Filename string `json:"filename"`
+			Frame    struct {
+				X int `json:"x"`
+				Y int `json:"y"`
+				W int `json:"w"`
+				H int `json:"h"`
+			} `json:"frame"`
+			Rotated bool `json:"rotated"`
+		} `json:"frames"`
+	}
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode sprite metadata")
+	}
+
+	file, err := assets.Open("static/cards.png")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open sprite sheet")
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode sprite sheet")
+	}
+
+	sheet := &SpriteSheet{image: img, frames: map[string]image.Rectangle{}}
+	for _, frame := range metadata.Frames {
+		if frame.Rotated {
+			return nil, errors.Errorf("sprite %q is rotated", frame.Filename)
+		}
+		sheet.frames[frame.Filename] = image.Rect(frame.Frame.X, frame.Frame.Y, frame.Frame.X+frame.Frame.W, frame.Frame.Y+frame.Frame.H)
+	}
+
+	return sheet, nil
+}
+
+// drawCard scales the sprite of the card name into r of dst.
+func (s *SpriteSheet) drawCard(dst draw.Image, r image.Rectangle, name string) error {
+	frame, ok := s.frames[name]
+	if !ok {
+		return errors.Errorf("no sprite for card %q", name)
+	}
+
+	draw.ApproxBiLinear.Scale(dst, r, s.image, frame, draw.Over, nil)
+
+	return nil
+}
+
+// getSpriteSheet returns the card sprites, loading them on first use.
+func (p *Plugin) getSpriteSheet() (*SpriteSheet, error) {
+	p.spritesOnce.Do(func() {
+		p.sprites, p.spritesErr = LoadSpriteSheet(staticAssets)
+	})
+
+	return p.sprites, p.spritesErr
+}
+
+// renderSnapshot draws the board of game as it is seen by the players, below
+// a header with the status of the game and the scores, and encodes it as a
+// PNG image.
+func (p *Plugin) renderSnapshot(game *Game) ([]byte, error) {
+	if game.Rows <= 0 || game.Columns <= 0 || game.Rows*game.Columns != len(game.CardValues) {
+		return nil, errors.Errorf("board of %dx%d cards does not hold %d cards", game.Rows, game.Columns, len(game.CardValues))
+	}
+
+	sprites, err := p.getSpriteSheet()
+	if err != nil {
+		return nil, err
+	}
+
+	scores := []string{}
+	for _, player := range game.Participants() {
+		scores = append(scores, fmt.Sprintf("@%s: %d", p.getUsername(player), game.Scores[player]))
+	}
+	lines := []string{p.describeStatus(game), strings.Join(scores, "   ")}
+
+	face := basicfont.Face7x13
+	gridWidth := game.Columns*snapshotCardWidth + (game.Columns-1)*snapshotGap
+	width := gridWidth
+	for _, line := range lines {
+		if lineWidth := font.MeasureString(face, line).Ceil(); lineWidth > width {
+			width = lineWidth
+		}
+	}
+	width += 2 * snapshotMargin
+	headerHeight := snapshotMargin + len(lines)*snapshotLineHeight
+	height := headerHeight + snapshotMargin/2 + game.Rows*snapshotCardHeight + (game.Rows-1)*snapshotGap + snapshotMargin
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), snapshotBackground, image.Point{}, draw.Src)
+
+	drawer := &font.Drawer{Dst: img, Src: snapshotText, Face: face}
+	for i, line := range lines {
+		drawer.Dot = fixed.P(snapshotMargin, snapshotMargin+(i+1)*snapshotLineHeight-face.Descent)
+		drawer.DrawString(line)
+	}
+
+	left := (width - gridWidth) / 2
+	top := headerHeight + snapshotMargin/2
+	for i := range game.CardValues {
+		x := left + (i%game.Columns)*(snapshotCardWidth+snapshotGap)
+		y := top + (i/game.Columns)*(snapshotCardHeight+snapshotGap)
+
+		name := CardBack
+		if game.CardFlipped[i] {
+			name = game.CardValues[i]
+		}
+
+		err = sprites.drawCard(img, image.Rect(x, y, x+snapshotCardWidth, y+snapshotCardHeight), name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	err = png.Encode(&b, img)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encode snapshot")
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSpriteSheet(t *testing.T) {
+	sprites, err := LoadSpriteSheet(staticAssets)
+	require.NoError(t, err)
+
+	assert.Contains(t, sprites.frames, CardBack)
+	for _, value := range GetCardPool() {
+		assert.Contains(t, sprites.frames, value)
+	}
+}
+
+func TestRenderSnapshot(t *testing.T) {
+	p := newTestPlugin(t)
+	game := newTestGame(t, p)
+	game.CardFlipped[0] = true
+	require.NoError(t, p.setGame(game))
+
+	snapshot, err := p.renderSnapshot(game)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(snapshot))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, img.Bounds().Dx(), 2*snapshotCardWidth+snapshotGap+2*snapshotMargin)
+	assert.Greater(t, img.Bounds().Dy(), 2*snapshotCardHeight+snapshotGap+2*snapshotMargin)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/game/game/snapshot.png", nil)
+	r.Header.Set("Mattermost-User-ID", "user1")
+	p.router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, snapshot, w.Body.Bytes())
+}
+
+func TestRenderSnapshotInvalidBoard(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableSummaryPosts: true})
+	api := p.API.(*fakeAPI)
+	game := newTestGame(t, p)
+	game.Rows, game.Columns = 0, 0
+
+	_, err := p.renderSnapshot(game)
+	assert.Error(t, err)
+
+	game.Over = true
+	p.postGameSummary(game)
+	require.Len(t, api.posts, 1)
+	assert.Empty(t, api.posts[0].FileIds)
+}
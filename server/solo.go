@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// SoloLeaderboard holds the best solo result of each user on a board size,
+// by user ID.
+type SoloLeaderboard map[string]*SoloResult
+
+// Rank returns the user IDs of the leaderboard sorted by their results.
+func (l SoloLeaderboard) Rank() []string {
+	userIDs := make([]string, 0, len(l))
+	for userID := range l {
+		userIDs = append(userIDs, userID)
+	}
+
+	sort.Slice(userIDs, func(i, j int) bool {
+		a, b := l[userIDs[i]], l[userIDs[j]]
+		if a.Better(b) != b.Better(a) {
+			return a.Better(b)
+		}
+		return userIDs[i] < userIDs[j]
+	})
+
+	return userIDs
+}
+
+// RankOf returns the rank of userID in the leaderboard, or zero if the user
+// has no result.
+func (l SoloLeaderboard) RankOf(userID string) int {
+	for i, rankedID := range l.Rank() {
+		if rankedID == userID {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// startSoloGame creates and begins a practice game of userID in channel c.
+func (p *Plugin) startSoloGame(userID string, c *model.Channel, difficulty string) (*Game, error) {
+	config := p.getConfiguration()
+	if !config.IsChannelTypeEnabled(c.Type) {
+		return nil, ErrChannelTypeDisabled
+	}
+
+	if difficulty == "" {
+		difficulty = config.DefaultDifficulty
+	}
+
+	err := p.checkGameLimit(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	game, err := p.NewGame([]string{userID}, c.Id, difficulty)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create game")
+	}
+	game.Solo = true
+	game.start()
+
+	err = p.addGame(game)
+	if err != nil {
+		return nil, err
+	}
+
+	return game, nil
+}
+
+func soloBestsKey(userID string) string {
+	return KeyPrefixSoloBests + userID
+}
+
+func soloLeaderboardKey(difficulty string) string {
+	return KeyPrefixSoloRanking + difficulty
+}
+
+// getSoloBests returns the personal bests of userID, by difficulty.
+func (p *Plugin) getSoloBests(userID string) (map[string]*SoloResult, error) {
+	bests := map[string]*SoloResult{}
+	err := p.mm.KV.Get(soloBestsKey(userID), &bests)
+	if err != nil {
+		return nil, err
+	}
+
+	return bests, nil
+}
+
+// getSoloLeaderboard returns the best result of every user on boards of
+// difficulty.
+func (p *Plugin) getSoloLeaderboard(difficulty string) (SoloLeaderboard, error) {
+	leaderboard := SoloLeaderboard{}
+	err := p.mm.KV.Get(soloLeaderboardKey(difficulty), &leaderboard)
+	if err != nil {
+		return nil, err
+	}
+
+	return leaderboard, nil
+}
+
+// recordSoloResult stores the result of a cleared solo game in the personal
+// bests of its player and in the solo leaderboard. It returns whether the
+// result is a new personal best.
+func (p *Plugin) recordSoloResult(game *Game) (bool, error) {
+	result := game.SoloResult()
+	if result == nil {
+		return false, nil
+	}
+
+	difficulty, ok := GetDifficulty(game.Rows, game.Columns)
+	if !ok {
+		return false, errors.Errorf("unknown board of %dx%d cards", game.Rows, game.Columns)
+	}
+	userID := game.Players[0]
+
+	best := false
+	err := p.mm.KV.SetAtomicWithRetries(soloBestsKey(userID), func(oldValue []byte) (interface{}, error) {
+		bests := map[string]*SoloResult{}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, &bests); err != nil {
+				return nil, err
+			}
+		}
+
+		best = result.Better(bests[difficulty])
+		if best {
+			bests[difficulty] = result
+		}
+
+		return bests, nil
+	})
+	if err != nil || !best {
+		return false, err
+	}
+
+	err = p.mm.KV.SetAtomicWithRetries(soloLeaderboardKey(difficulty), func(oldValue []byte) (interface{}, error) {
+		leaderboard := SoloLeaderboard{}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, &leaderboard); err != nil {
+				return nil, err
+			}
+		}
+
+		if result.Better(leaderboard[userID]) {
+			leaderboard[userID] = result
+		}
+
+		return leaderboard, nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// getRankedSoloLeaderboard returns a page of the solo leaderboard of
+// difficulty.
+func (p *Plugin) getRankedSoloLeaderboard(difficulty string, page, perPage int) (*SoloLeaderboardResponse, error) {
+	leaderboard, err := p.getSoloLeaderboard(difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SoloLeaderboardResponse{
+		Difficulty: difficulty,
+		Total:      len(leaderboard),
+		Entries:    p.getSoloLeaderboardEntries(leaderboard, page, perPage),
+	}, nil
+}
+
+// getSoloLeaderboardEntries returns a page of the ranked results of
+// leaderboard.
+func (p *Plugin) getSoloLeaderboardEntries(leaderboard SoloLeaderboard, page, perPage int) []SoloLeaderboardEntry {
+	userIDs := leaderboard.Rank()
+	entries := []SoloLeaderboardEntry{}
+
+	start := page * perPage
+	if start >= len(userIDs) {
+		return entries
+	}
+	end := start + perPage
+	if end > len(userIDs) {
+		end = len(userIDs)
+	}
+
+	for i := start; i < end; i++ {
+		entries = append(entries, SoloLeaderboardEntry{
+			Rank:       i + 1,
+			UserID:     userIDs[i],
+			Username:   p.getUsername(userIDs[i]),
+			SoloResult: *leaderboard[userIDs[i]],
+		})
+	}
+
+	return entries
+}
+
+// formatDuration formats a duration in milliseconds to the second.
+func formatDuration(milliseconds int64) string {
+	return (time.Duration(milliseconds) * time.Millisecond).Round(time.Second).String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoloGame(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnablePublicChannels: true})
+
+	game, err := p.startSoloGame("user1", &model.Channel{Id: "channel", Type: model.CHANNEL_OPEN}, DifficultyEasy)
+	require.NoError(t, err)
+	assert.True(t, game.Started)
+	assert.Equal(t, []string{"user1"}, game.Players)
+
+	// Miss once, then clear the board pair by pair.
+	pairs := map[string][]int{}
+	for i, value := range game.CardValues {
+		pairs[value] = append(pairs[value], i)
+	}
+	seq := 0
+	flip := func(index int) {
+		require.Equal(t, http.StatusOK, flipCard(p, "user1", game.GID, index, seq))
+		seq++
+	}
+	first := pairs[game.CardValues[0]]
+	for i := range game.CardValues {
+		if game.CardValues[i] != game.CardValues[0] {
+			flip(first[0])
+			flip(i)
+			break
+		}
+	}
+	for _, indexes := range pairs {
+		flip(indexes[0])
+		flip(indexes[1])
+	}
+
+	_, err = p.getGame(game.GID)
+	assert.ErrorIs(t, err, ErrGameNotFound)
+
+	bests, err := p.getSoloBests("user1")
+	require.NoError(t, err)
+	require.NotNil(t, bests[DifficultyEasy])
+	assert.Equal(t, 7, bests[DifficultyEasy].Moves)
+	assert.Equal(t, game.GID, bests[DifficultyEasy].GID)
+
+	leaderboard, err := p.getRankedSoloLeaderboard(DifficultyEasy, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, leaderboard.Entries, 1)
+	assert.Equal(t, "user1", leaderboard.Entries[0].UserID)
+
+	stats, err := p.getPlayerStats("user1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.GamesPlayed)
+}
+
+func TestSoloLeaderboardRank(t *testing.T) {
+	leaderboard := SoloLeaderboard{
+		"user1": {Moves: 8, Duration: 1000},
+		"user2": {Moves: 6, Duration: 5000},
+		"user3": {Moves: 8, Duration: 900},
+	}
+
+	assert.Equal(t, []string{"user2", "user3", "user1"}, leaderboard.Rank())
+}
@@ -1,19 +1,73 @@
 package main
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
 
-func (p *Plugin) getGame(gID string) (*Game, error) {
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+var ErrGameNotFound = errors.New("not found")
+
+func decodeGame(data []byte) (*Game, error) {
+	if len(data) == 0 {
+		return nil, ErrGameNotFound
+	}
+
+	game, _, err := decodeAndMigrateGame(data)
+
+	return game, err
+}
+
+// decodeAndMigrateGame decodes a stored game and upgrades it, and reports
+// whether it has to be stored again.
+func decodeAndMigrateGame(data []byte) (*Game, bool, error) {
 	game := &Game{}
-	err := p.mm.KV.Get(gID, &game)
+	err := json.Unmarshal(data, game)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return game, game.migrate(), nil
+}
+
+func (p *Plugin) getGame(gID string) (*Game, error) {
+	var data []byte
+	err := p.mm.KV.Get(gID, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrGameNotFound
+	}
+
+	game, upgraded, err := decodeAndMigrateGame(data)
 	if err != nil {
 		return nil, err
 	}
-	if game == nil {
-		return nil, errors.New("not found")
+	if upgraded {
+		p.upgradeGame(game, data)
 	}
+
 	return game, nil
 }
 
+// upgradeGame stores the migrated game in place of its old data, unless it
+// changed in the meantime, and adds it to the active games, as games stored
+// before boards had a size are missing from them.
+func (p *Plugin) upgradeGame(game *Game, oldData []byte) {
+	_, err := p.mm.KV.Set(game.GID, game, pluginapi.SetAtomic(oldData))
+	if err != nil {
+		p.mm.Log.Warn("Cannot store upgraded game", "gameID", game.GID, "err", err.Error())
+	}
+
+	err = p.addActiveGame(game.GID)
+	if err != nil {
+		p.mm.Log.Warn("Cannot add upgraded game to the active games", "gameID", game.GID, "err", err.Error())
+	}
+}
+
 func (p *Plugin) setGame(game *Game) error {
 	_, err := p.mm.KV.Set(game.GID, game)
 	if err != nil {
@@ -23,28 +77,334 @@ func (p *Plugin) setGame(game *Game) error {
 	return nil
 }
 
+// updateGame atomically applies update to the stored game, bumps its move
+// sequence number and refreshes its board post. The update may be called
+// several times if other writers modify the game concurrently, so it must
+// only modify the game it receives.
+func (p *Plugin) updateGame(gID string, update func(game *Game) error) (*Game, error) {
+	var game *Game
+	err := p.mm.KV.SetAtomicWithRetries(gID, func(oldValue []byte) (interface{}, error) {
+		var err error
+		game, err = decodeGame(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = update(game); err != nil {
+			return nil, err
+		}
+		game.Seq++
+
+		return game, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.updateBoardPost(game)
+
+	return game, nil
+}
+
 func (p *Plugin) removeGame(game *Game) error {
-	return p.mm.KV.Delete(game.GID)
+	err := p.mm.KV.Delete(game.GID)
+	if err != nil {
+		return err
+	}
+
+	err = p.removeActiveGame(game.GID)
+	if err != nil {
+		return err
+	}
+
+	return p.removeChannelGame(game.ChannelID, game.GID)
+}
+
+func channelGamesKey(channelID string) string {
+	return KeyPrefixChannelGames + channelID
+}
+
+func (p *Plugin) getChannelGameIDs(channelID string) ([]string, error) {
+	gameIDs := []string{}
+	err := p.mm.KV.Get(channelGamesKey(channelID), &gameIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return gameIDs, nil
+}
+
+// updateGameIDs atomically applies update to the list of game IDs stored at
+// key. Empty lists are deleted.
+func (p *Plugin) updateGameIDs(key string, update func(gameIDs []string) []string) error {
+	return p.mm.KV.SetAtomicWithRetries(key, func(oldValue []byte) (interface{}, error) {
+		gameIDs := []string{}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, &gameIDs); err != nil {
+				return nil, err
+			}
+		}
+
+		gameIDs = update(gameIDs)
+		if len(gameIDs) == 0 {
+			return nil, nil
+		}
+
+		return gameIDs, nil
+	})
+}
+
+func (p *Plugin) addGameID(key, gID string) error {
+	return p.updateGameIDs(key, func(gameIDs []string) []string {
+		if containsString(gameIDs, gID) {
+			return gameIDs
+		}
+		return append(gameIDs, gID)
+	})
+}
+
+func (p *Plugin) removeGameID(key, gID string) error {
+	return p.updateGameIDs(key, func(gameIDs []string) []string {
+		return removeString(gameIDs, gID)
+	})
+}
+
+func (p *Plugin) addChannelGame(channelID, gID string) error {
+	return p.addGameID(channelGamesKey(channelID), gID)
+}
+
+func (p *Plugin) removeChannelGame(channelID, gID string) error {
+	return p.removeGameID(channelGamesKey(channelID), gID)
+}
+
+// getActiveGameIDs returns the IDs of every game that is not finished yet,
+// across all channels.
+func (p *Plugin) getActiveGameIDs() ([]string, error) {
+	gameIDs := []string{}
+	err := p.mm.KV.Get(KeyActiveGames, &gameIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return gameIDs, nil
 }
 
+func (p *Plugin) addActiveGame(gID string) error {
+	return p.addGameID(KeyActiveGames, gID)
+}
+
+func (p *Plugin) removeActiveGame(gID string) error {
+	return p.removeGameID(KeyActiveGames, gID)
+}
+
+// getChannelGames returns the active games of channelID, in creation order.
+func (p *Plugin) getChannelGames(channelID string) ([]*Game, error) {
+	gameIDs, err := p.getChannelGameIDs(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Games created before game IDs were generated are stored under the
+	// channel ID and missing from the index.
+	gameIDs = append([]string{channelID}, gameIDs...)
+
+	games := []*Game{}
+	for _, gID := range gameIDs {
+		game, err := p.getGame(gID)
+		if errors.Is(err, ErrGameNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+
+	return games, nil
+}
+
+func statsKey(userID string) string {
+	return KeyPrefixStats + userID
+}
+
+// getPlayerStats returns the stats of userID. Stats stored before they were
+// namespaced are read from the raw user ID key.
 func (p *Plugin) getPlayerStats(userID string) (*PlayerStats, error) {
+	var data []byte
+	err := p.mm.KV.Get(statsKey(userID), &data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		err = p.mm.KV.Get(userID, &data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	stats := &PlayerStats{}
-	err := p.mm.KV.Get(userID, &stats)
+	if len(data) == 0 {
+		return stats, nil
+	}
+
+	err = json.Unmarshal(data, stats)
 	if err != nil {
 		return nil, err
 	}
-	if stats == nil {
-		return &PlayerStats{}, nil
+	// Legacy stats only counted wins.
+	if stats.GamesPlayed < stats.Wins {
+		stats.GamesPlayed = stats.Wins
+	}
+
+	return stats, nil
+}
+
+// updatePlayerStats atomically applies update to the stats of userID and
+// returns the stored result.
+func (p *Plugin) updatePlayerStats(userID string, update func(stats *PlayerStats)) (*PlayerStats, error) {
+	var stats *PlayerStats
+	err := p.mm.KV.SetAtomicWithRetries(statsKey(userID), func(oldValue []byte) (interface{}, error) {
+		if len(oldValue) == 0 {
+			var err error
+			stats, err = p.getPlayerStats(userID)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			stats = &PlayerStats{}
+			if err := json.Unmarshal(oldValue, stats); err != nil {
+				return nil, err
+			}
+		}
+
+		update(stats)
+
+		return stats, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return stats, nil
 }
 
-func (p *Plugin) setPlayerStats(userID string, stats *PlayerStats) error {
-	_, err := p.mm.KV.Set(userID, stats)
+func historyGameKey(gID string) string {
+	return KeyPrefixHistoryGame + gID
+}
+
+func historyUserKey(userID string) string {
+	return KeyPrefixHistoryUser + userID
+}
+
+// archiveGame stores a finished game in the history of every participant.
+func (p *Plugin) archiveGame(game *Game) error {
+	_, err := p.mm.KV.Set(historyGameKey(game.GID), game)
 	if err != nil {
 		return err
 	}
 
+	for _, userID := range game.Participants() {
+		err = p.mm.KV.SetAtomicWithRetries(historyUserKey(userID), func(oldValue []byte) (interface{}, error) {
+			gameIDs := []string{}
+			if len(oldValue) != 0 {
+				if err := json.Unmarshal(oldValue, &gameIDs); err != nil {
+					return nil, err
+				}
+			}
+
+			gameIDs = append([]string{game.GID}, gameIDs...)
+			if len(gameIDs) > MaxHistorySize {
+				gameIDs = gameIDs[:MaxHistorySize]
+			}
+
+			return gameIDs, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func (p *Plugin) getArchivedGame(gID string) (*Game, error) {
+	var data []byte
+	err := p.mm.KV.Get(historyGameKey(gID), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeGame(data)
+}
+
+// getHistory returns a page of the finished games of userID, most recent
+// first.
+func (p *Plugin) getHistory(userID string, page, perPage int) ([]*Game, error) {
+	gameIDs := []string{}
+	err := p.mm.KV.Get(historyUserKey(userID), &gameIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	start := page * perPage
+	if start >= len(gameIDs) {
+		return []*Game{}, nil
+	}
+	end := start + perPage
+	if end > len(gameIDs) {
+		end = len(gameIDs)
+	}
+
+	games := []*Game{}
+	for _, gID := range gameIDs[start:end] {
+		game, err := p.getArchivedGame(gID)
+		if errors.Is(err, ErrGameNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+
+	return games, nil
+}
+
+func seriesKey(seriesID string) string {
+	return KeyPrefixSeries + seriesID
+}
+
+// getSeries returns the series seriesID, which is empty if no game of the
+// series finished yet.
+func (p *Plugin) getSeries(seriesID string) (*Series, error) {
+	series := &Series{ID: seriesID, Wins: map[string]int{}}
+	err := p.mm.KV.Get(seriesKey(seriesID), series)
+	if err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// updateSeries atomically applies update to the series seriesID and returns
+// the stored result.
+func (p *Plugin) updateSeries(seriesID string, update func(series *Series)) (*Series, error) {
+	var series *Series
+	err := p.mm.KV.SetAtomicWithRetries(seriesKey(seriesID), func(oldValue []byte) (interface{}, error) {
+		series = &Series{ID: seriesID, Wins: map[string]int{}}
+		if len(oldValue) != 0 {
+			if err := json.Unmarshal(oldValue, series); err != nil {
+				return nil, err
+			}
+		}
+
+		update(series)
+
+		return series, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI is an in-memory implementation of the parts of the plugin API used
+// by the game logic. Calling any other method panics.
+type fakeAPI struct {
+	plugin.API
+
+	lock  sync.Mutex
+	kv    map[string][]byte
+	posts []*model.Post
+	files map[string][]byte
+	// readers holds the users that can read each channel, besides the
+	// players of its games.
+	readers map[string][]string
+	// failKey makes writing that key fail.
+	failKey string
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{kv: map[string][]byte{}, files: map[string][]byte{}, readers: map[string][]string{}}
+}
+
+func (a *fakeAPI) KVGet(key string) ([]byte, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.kv[key], nil
+}
+
+func (a *fakeAPI) KVSetWithOptions(key string, value []byte, options model.PluginKVSetOptions) (bool, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.failKey != "" && key == a.failKey {
+		return false, model.NewAppError("KVSetWithOptions", "fake.kv_set.app_error", nil, "", http.StatusInternalServerError)
+	}
+
+	if options.Atomic && !bytes.Equal(a.kv[key], options.OldValue) {
+		return false, nil
+	}
+
+	if value == nil {
+		delete(a.kv, key)
+	} else {
+		a.kv[key] = value
+	}
+
+	return true, nil
+}
+
+func (a *fakeAPI) LogDebug(string, ...interface{}) {}
+func (a *fakeAPI) LogInfo(string, ...interface{})  {}
+func (a *fakeAPI) LogWarn(string, ...interface{})  {}
+func (a *fakeAPI) LogError(string, ...interface{}) {}
+
+func (a *fakeAPI) GetUser(userID string) (*model.User, *model.AppError) {
+	return &model.User{Id: userID, Username: userID}, nil
+}
+
+func (a *fakeAPI) GetUserByUsername(username string) (*model.User, *model.AppError) {
+	if username == "nobody" {
+		return nil, model.NewAppError("GetUserByUsername", "fake.user.not_found", nil, "", http.StatusNotFound)
+	}
+
+	return &model.User{Id: username, Username: username}, nil
+}
+
+func (a *fakeAPI) PublishWebSocketEvent(string, map[string]interface{}, *model.WebsocketBroadcast) {}
+
+func (a *fakeAPI) GetChannel(channelID string) (*model.Channel, *model.AppError) {
+	if strings.Contains(channelID, "__") {
+		return &model.Channel{Id: channelID, Name: channelID, Type: model.CHANNEL_DIRECT}, nil
+	}
+
+	return &model.Channel{Id: channelID, Name: channelID, Type: model.CHANNEL_OPEN}, nil
+}
+
+func (a *fakeAPI) HasPermissionToChannel(userID, channelID string, permission *model.Permission) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return permission == model.PERMISSION_READ_CHANNEL && containsString(a.readers[channelID], userID)
+}
+
+func (a *fakeAPI) GetDirectChannel(userID1, userID2 string) (*model.Channel, *model.AppError) {
+	return &model.Channel{Id: userID1 + "__" + userID2, Name: userID1 + "__" + userID2, Type: model.CHANNEL_DIRECT}, nil
+}
+
+func (a *fakeAPI) CreatePost(post *model.Post) (*model.Post, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.posts = append(a.posts, post)
+
+	created := post.Clone()
+	created.Id = model.NewId()
+
+	return created, nil
+}
+
+func (a *fakeAPI) UpdatePost(post *model.Post) (*model.Post, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.posts = append(a.posts, post)
+
+	return post.Clone(), nil
+}
+
+func (a *fakeAPI) DeletePost(postID string) *model.AppError {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	kept := []*model.Post{}
+	for _, post := range a.posts {
+		if post.Id != postID {
+			kept = append(kept, post)
+		}
+	}
+	a.posts = kept
+
+	return nil
+}
+
+func (a *fakeAPI) UploadFile(data []byte, channelID, filename string) (*model.FileInfo, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	info := &model.FileInfo{Id: model.NewId(), Name: filename}
+	a.files[info.Id] = data
+
+	return info, nil
+}
+
+func newTestPlugin(t *testing.T) *Plugin {
+	t.Helper()
+
+	api := newFakeAPI()
+	p := &Plugin{BotUserID: "bot"}
+	p.SetAPI(api)
+	p.mm = pluginapi.NewClient(api)
+	p.initializeAPI(staticAssets)
+
+	return p
+}
+
+func newTestGame(t *testing.T, p *Plugin) *Game {
+	t.Helper()
+
+	game := &Game{
+		GID:         "game",
+		CardValues:  []string{"joker", "joker", "heartsAce", "heartsAce"},
+		CardFlipped: []bool{false, false, false, false},
+		Rows:        2,
+		Columns:     2,
+		LastFlipped: -1,
+		Owner:       "user1",
+		Players:     []string{"user1", "user2"},
+		Started:     true,
+		Scores:      map[string]int{"user1": 0, "user2": 0},
+	}
+	require.NoError(t, p.setGame(game))
+
+	return game
+}
+
+func flipCard(p *Plugin, userID, gameID string, index, seq int) int {
+	w := httptest.NewRecorder()
+	body := strings.NewReader(fmt.Sprintf(`{"index": %d, "seq": %d}`, index, seq))
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/game/"+gameID+"/flip", body)
+	r.Header.Set("Mattermost-User-ID", userID)
+
+	p.router.ServeHTTP(w, r)
+
+	return w.Result().StatusCode
+}
+
+func TestUpdateGameConcurrentFlips(t *testing.T) {
+	p := newTestPlugin(t)
+	newTestGame(t, p)
+
+	const workers = 50
+	statuses := make(chan int, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- flipCard(p, "user1", "game", 0, 0)
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	succeeded := 0
+	for status := range statuses {
+		if status == http.StatusOK {
+			succeeded++
+			continue
+		}
+		assert.Contains(t, []int{http.StatusConflict, http.StatusBadRequest}, status)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	game, err := p.getGame("game")
+	require.NoError(t, err)
+	assert.Equal(t, 1, game.Seq)
+	assert.Equal(t, 0, game.LastFlipped)
+	assert.Equal(t, []bool{true, false, false, false}, game.CardFlipped)
+}
+
+func TestUpdateGameNoLostUpdates(t *testing.T) {
+	p := newTestPlugin(t)
+	newTestGame(t, p)
+
+	const workers = 20
+	var succeeded int
+	var lock sync.Mutex
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.updateGame("game", func(game *Game) error {
+				game.Scores["user1"]++
+				return nil
+			})
+			if err == nil {
+				lock.Lock()
+				succeeded++
+				lock.Unlock()
+				return
+			}
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+
+	game, err := p.getGame("game")
+	require.NoError(t, err)
+	assert.Equal(t, succeeded, game.Scores["user1"])
+	assert.Equal(t, succeeded, game.Seq)
+}
+
+func TestFlipCardRejectsStaleSeq(t *testing.T) {
+	p := newTestPlugin(t)
+	newTestGame(t, p)
+
+	assert.Equal(t, http.StatusOK, flipCard(p, "user1", "game", 0, 0))
+	assert.Equal(t, http.StatusConflict, flipCard(p, "user1", "game", 1, 0))
+	assert.Equal(t, http.StatusBadRequest, flipCard(p, "user2", "game", 1, 1))
+	assert.Equal(t, http.StatusOK, flipCard(p, "user1", "game", 1, 1))
+
+	game, err := p.getGame("game")
+	require.NoError(t, err)
+	assert.Equal(t, 2, game.Seq)
+	assert.Equal(t, 1, game.Scores["user1"])
+	assert.Equal(t, "user1", game.CurrentPlayer())
+}
+
+func TestUpdateGameNotFound(t *testing.T) {
+	p := newTestPlugin(t)
+
+	_, err := p.updateGame("missing", func(game *Game) error { return nil })
+	assert.ErrorIs(t, err, ErrGameNotFound)
+}
+
+func TestChannelGames(t *testing.T) {
+	p := newTestPlugin(t)
+
+	var gameIDs []string
+	for i := 0; i < 3; i++ {
+		game, err := p.NewGame([]string{"user1", "user2"}, "channel", DifficultyEasy)
+		require.NoError(t, err)
+		require.NoError(t, p.setGame(game))
+		require.NoError(t, p.addChannelGame("channel", game.GID))
+		gameIDs = append(gameIDs, game.GID)
+	}
+
+	games, err := p.getChannelGames("channel")
+	require.NoError(t, err)
+	require.Len(t, games, 3)
+	for i, game := range games {
+		assert.Equal(t, gameIDs[i], game.GID)
+		assert.Equal(t, "channel", game.ChannelID)
+	}
+
+	require.NoError(t, p.removeGame(games[1]))
+
+	games, err = p.getChannelGames("channel")
+	require.NoError(t, err)
+	require.Len(t, games, 2)
+	assert.Equal(t, gameIDs[0], games[0].GID)
+	assert.Equal(t, gameIDs[2], games[1].GID)
+
+	ids, err := p.getChannelGameIDs("channel")
+	require.NoError(t, err)
+	assert.Equal(t, []string{gameIDs[0], gameIDs[2]}, ids)
+}
+
+func TestArchiveGameHistory(t *testing.T) {
+	p := newTestPlugin(t)
+
+	var gameIDs []string
+	for i := 0; i < 3; i++ {
+		game, err := p.NewGame([]string{"user1", "user2"}, "channel", DifficultyEasy)
+		require.NoError(t, err)
+		require.NoError(t, p.archiveGame(game))
+		gameIDs = append(gameIDs, game.GID)
+	}
+
+	games, err := p.getHistory("user2", 0, 2)
+	require.NoError(t, err)
+	require.Len(t, games, 2)
+	assert.Equal(t, gameIDs[2], games[0].GID)
+	assert.Equal(t, gameIDs[1], games[1].GID)
+
+	games, err = p.getHistory("user1", 1, 2)
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	assert.Equal(t, gameIDs[0], games[0].GID)
+
+	games, err = p.getHistory("user3", 0, 2)
+	require.NoError(t, err)
+	assert.Empty(t, games)
+}
+
+func postGameAction(p *Plugin, userID, gameID, action string) int {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/game/"+gameID+"/"+action, nil)
+	r.Header.Set("Mattermost-User-ID", userID)
+
+	p.router.ServeHTTP(w, r)
+
+	return w.Result().StatusCode
+}
+
+func TestDrawAndResignEndpoints(t *testing.T) {
+	p := newTestPlugin(t)
+	newTestGame(t, p)
+
+	assert.Equal(t, http.StatusBadRequest, postGameAction(p, "user2", "game", "accept-draw"))
+	assert.Equal(t, http.StatusOK, postGameAction(p, "user1", "game", "offer-draw"))
+	assert.Equal(t, http.StatusForbidden, postGameAction(p, "user3", "game", "accept-draw"))
+	assert.Equal(t, http.StatusOK, postGameAction(p, "user2", "game", "accept-draw"))
+
+	_, err := p.getGame("game")
+	assert.ErrorIs(t, err, ErrGameNotFound)
+	stats, err := p.getPlayerStats("user1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Draws)
+	assert.Equal(t, 1, stats.Opponents["user2"].Draws)
+	stats, err = p.getPlayerStats("user2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Draws)
+
+	newTestGame(t, p)
+	assert.Equal(t, http.StatusOK, postGameAction(p, "user2", "game", "resign"))
+	assert.Equal(t, http.StatusNotFound, postGameAction(p, "user1", "game", "resign"))
+
+	stats, err = p.getPlayerStats("user1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Wins)
+	stats, err = p.getPlayerStats("user2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Losses)
+
+	games, err := p.getHistory("user1", 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, games, 2)
+}
+
+func TestResignNotifications(t *testing.T) {
+	p := newTestPlugin(t)
+	p.setConfiguration(&configuration{EnableNotifications: true})
+	api := p.API.(*fakeAPI)
+	game := newTestGame(t, p)
+	game.Players = []string{"user1", "user2", "user3"}
+	game.Scores["user3"] = 0
+	require.NoError(t, p.setGame(game))
+
+	_, err := p.offerDraw(game.GID, "user1")
+	require.NoError(t, err)
+	_, err = p.acceptDraw(game.GID, "user2")
+	require.NoError(t, err)
+	api.posts = nil
+
+	game, err = p.resignGame(game.GID, "user3")
+	require.NoError(t, err)
+	require.True(t, game.Drawn)
+	require.Len(t, api.posts, 2)
+	for _, post := range api.posts {
+		assert.True(t, strings.HasSuffix(post.Message, " The game is a draw."), post.Message)
+	}
+
+	newTestGame(t, p)
+	api.posts = nil
+	_, err = p.resignGame("game", "user2")
+	require.NoError(t, err)
+	require.Len(t, api.posts, 1)
+	assert.Equal(t, "bot__user1", api.posts[0].ChannelId)
+	assert.Contains(t, api.posts[0].Message, "You win!")
+}
+
+func TestGetBaselineGame(t *testing.T) {
+	p := newTestPlugin(t)
+	api := p.API.(*fakeAPI)
+
+	// A game as stored before board sizes and game IDs.
+	api.kv["channel1"] = []byte(`{"GID":"channel1",` +
+		`"CardValues":["joker","heartsAce","diamondsAce","clubsAce","spadesAce","heartsKing","joker","heartsAce","diamondsAce","clubsAce","spadesAce","heartsKing"],` +
+		`"CardFlipped":[false,false,false,false,false,false,false,false,false,false,false,false],` +
+		`"LastFlipped":-1,"CurrentPlayer":"user1","OtherPlayer":"user2","Scores":{"user1":0,"user2":0},"Streak":0}`)
+
+	game, err := p.getGame("channel1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, game.Rows)
+	assert.Equal(t, 3, game.Columns)
+	assert.Equal(t, []string{"user1", "user2"}, game.Players)
+	assert.NotZero(t, game.ActiveAt)
+
+	gameIDs, err := p.getActiveGameIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"channel1"}, gameIDs)
+	stored, err := decodeGame(api.kv["channel1"])
+	require.NoError(t, err)
+	assert.Equal(t, 4, stored.Rows)
+
+	_, err = p.renderSnapshot(game)
+	require.NoError(t, err)
+
+	p.MessageHasBeenPosted(nil, &model.Post{UserId: "user1", ChannelId: "channel1", Message: "flip 1 7"})
+	require.Len(t, api.posts, 1)
+	assert.Contains(t, api.posts[0].Message, "It is a pair!")
+
+	game, err = p.getGame("channel1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, game.Scores["user1"])
+	gameIDs, err = p.getActiveGameIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"channel1"}, gameIDs)
+}
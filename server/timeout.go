@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// timeoutJobKey identifies the turn timeout job across the cluster.
+	timeoutJobKey = "turn_timeout_job"
+	// timeoutJobInterval is how often games are checked for timeouts.
+	timeoutJobInterval = time.Minute
+)
+
+// errNothingToDo aborts an update when the game changed since it was checked.
+var errNothingToDo = errors.New("nothing to do")
+
+// scheduleTimeoutJob starts the job that enforces turn time limits. The
+// cluster package makes sure a single server runs it at a time.
+func (p *Plugin) scheduleTimeoutJob() error {
+	job, err := cluster.Schedule(p.API, timeoutJobKey, cluster.MakeWaitForInterval(timeoutJobInterval), p.checkTimeouts)
+	if err != nil {
+		return err
+	}
+	p.timeoutJob = job
+
+	return nil
+}
+
+// checkTimeouts reminds, skips or forfeits the players that ran out of time,
+// removes abandoned games, expires unanswered invitations and posts the
+// results of the last daily challenge.
+func (p *Plugin) checkTimeouts() {
+	gameIDs, err := p.getActiveGameIDs()
+	if err != nil {
+		p.mm.Log.Warn("Cannot get active games", "err", err.Error())
+		return
+	}
+
+	now := model.GetMillis()
+	p.expireInvitations(now)
+	p.expireMatchmaking(now)
+	p.postDailyResults(time.Now())
+
+	for _, gID := range gameIDs {
+		game, err := p.getGame(gID)
+		if errors.Is(err, ErrGameNotFound) {
+			_ = p.removeActiveGame(gID)
+			continue
+		}
+		if err != nil {
+			p.mm.Log.Warn("Cannot get game", "gameID", gID, "err", err.Error())
+			continue
+		}
+
+		err = p.checkGameTimeout(game, now)
+		if err != nil && !errors.Is(err, errNothingToDo) {
+			p.mm.Log.Warn("Cannot check game timeout", "gameID", gID, "err", err.Error())
+		}
+	}
+}
+
+// checkGameTimeout applies the configured timeouts to game at time now, in
+// milliseconds.
+func (p *Plugin) checkGameTimeout(game *Game, now int64) error {
+	config := p.getConfiguration()
+	if game.ActiveAt == 0 || game.Over {
+		return nil
+	}
+	idle := time.Duration(now-game.ActiveAt) * time.Millisecond
+
+	if config.AbandonedGameDays > 0 && idle >= time.Duration(config.AbandonedGameDays)*24*time.Hour {
+		return p.removeAbandonedGame(game)
+	}
+
+	if !game.Started || game.Solo {
+		return nil
+	}
+
+	// The bot never runs out of time, but its turn is lost if the server
+	// restarts while it plays.
+	if game.CurrentPlayer() == p.BotUserID {
+		if idle >= botThinkingTime+botThinkingJitter {
+			p.startBotTurn(game)
+		}
+		return nil
+	}
+
+	if config.TurnTimeoutMinutes <= 0 {
+		return nil
+	}
+	timeout := time.Duration(config.TurnTimeoutMinutes) * time.Minute
+
+	if idle >= timeout {
+		switch config.TurnTimeoutAction {
+		case TimeoutActionSkip:
+			return p.skipTimedOutTurn(game)
+		case TimeoutActionForfeit:
+			return p.forfeitTimedOutGame(game)
+		}
+	}
+
+	if idle >= timeout/2 && !game.Reminded {
+		return p.remindPlayer(game, timeout-idle)
+	}
+
+	return nil
+}
+
+// updateIdleGame atomically applies update to game as long as nobody acted on
+// it since it was read.
+func (p *Plugin) updateIdleGame(game *Game, update func(game *Game) error) (*Game, error) {
+	return p.updateGame(game.GID, func(stored *Game) error {
+		if stored.ActiveAt != game.ActiveAt || stored.Reminded != game.Reminded || stored.Over {
+			return errNothingToDo
+		}
+		return update(stored)
+	})
+}
+
+func (p *Plugin) remindPlayer(game *Game, left time.Duration) error {
+	game, err := p.updateIdleGame(game, func(game *Game) error {
+		game.Reminded = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("It is your turn in the memory game in ~%s.", p.getChannelName(game.ChannelID))
+	if left > 0 {
+		message += fmt.Sprintf(" You have %d minutes left to move.", int(left.Round(time.Minute)/time.Minute))
+	}
+	p.notify(game.CurrentPlayer(), message)
+
+	return nil
+}
+
+func (p *Plugin) skipTimedOutTurn(game *Game) error {
+	skipped := game.CurrentPlayer()
+	game, err := p.updateIdleGame(game, func(game *Game) error {
+		return game.SkipTurn()
+	})
+	if err != nil {
+		return err
+	}
+
+	p.sendResyncToPlayers(game)
+	p.startBotTurn(game)
+	p.notify(skipped, fmt.Sprintf("You ran out of time in the memory game in ~%s, so your turn was skipped.", p.getChannelName(game.ChannelID)))
+
+	return nil
+}
+
+func (p *Plugin) forfeitTimedOutGame(game *Game) error {
+	forfeited := game.CurrentPlayer()
+	game, err := p.updateIdleGame(game, func(game *Game) error {
+		return game.Resign(forfeited)
+	})
+	if err != nil {
+		return err
+	}
+
+	if game.Over {
+		p.finishGame(game)
+	}
+
+	p.sendResyncToPlayers(game)
+	p.startBotTurn(game)
+	p.notify(forfeited, fmt.Sprintf("You ran out of time in the memory game in ~%s, so you forfeited it.", p.getChannelName(game.ChannelID)))
+
+	return nil
+}
+
+// removeAbandonedGame ends game before removing it, so that its board post no
+// longer offers moves.
+func (p *Plugin) removeAbandonedGame(game *Game) error {
+	game, err := p.updateIdleGame(game, func(game *Game) error {
+		game.Abandoned = true
+		game.end()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.removeGame(game)
+	if err != nil {
+		return err
+	}
+
+	for _, player := range game.Players {
+		p.notify(player, fmt.Sprintf("The memory game in ~%s was removed after being abandoned.", p.getChannelName(game.ChannelID)))
+	}
+
+	return nil
+}
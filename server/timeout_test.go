@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckGameTimeout(t *testing.T) {
+	minutes := func(m int) int64 { return int64(time.Duration(m) * time.Minute / time.Millisecond) }
+
+	t.Run("remind then skip", func(t *testing.T) {
+		p := newTestPlugin(t)
+		p.setConfiguration(&configuration{TurnTimeoutMinutes: 10, TurnTimeoutAction: TimeoutActionSkip, EnableNotifications: true})
+		game := newTestGame(t, p)
+		game.ActiveAt = 1
+		require.NoError(t, p.setGame(game))
+
+		require.NoError(t, p.checkGameTimeout(game, 1+minutes(4)))
+		game, err := p.getGame("game")
+		require.NoError(t, err)
+		assert.False(t, game.Reminded)
+
+		require.NoError(t, p.checkGameTimeout(game, 1+minutes(5)))
+		require.Len(t, p.API.(*fakeAPI).posts, 1)
+		assert.Equal(t, "bot__user1", p.API.(*fakeAPI).posts[0].ChannelId)
+		assert.ErrorIs(t, p.checkGameTimeout(game, 1+minutes(6)), errNothingToDo)
+		game, err = p.getGame("game")
+		require.NoError(t, err)
+		assert.True(t, game.Reminded)
+		assert.NoError(t, p.checkGameTimeout(game, 1+minutes(6)))
+
+		require.NoError(t, p.checkGameTimeout(game, 1+minutes(10)))
+		game, err = p.getGame("game")
+		require.NoError(t, err)
+		assert.Equal(t, "user2", game.CurrentPlayer())
+		assert.False(t, game.Reminded)
+		assert.Equal(t, EventTypeSkip, game.Events[len(game.Events)-1].Type)
+	})
+
+	t.Run("forfeit", func(t *testing.T) {
+		p := newTestPlugin(t)
+		p.setConfiguration(&configuration{TurnTimeoutMinutes: 10, TurnTimeoutAction: TimeoutActionForfeit})
+		game := newTestGame(t, p)
+		game.ActiveAt = 1
+		require.NoError(t, p.setGame(game))
+
+		require.NoError(t, p.checkGameTimeout(game, 1+minutes(10)))
+		_, err := p.getGame("game")
+		assert.ErrorIs(t, err, ErrGameNotFound)
+
+		archived, err := p.getArchivedGame("game")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user1"}, archived.Resignations)
+		assert.Equal(t, "user2", archived.Winner())
+	})
+
+	t.Run("forfeit passes the turn to the bot", func(t *testing.T) {
+		p := newTestPlugin(t)
+		p.setConfiguration(&configuration{TurnTimeoutMinutes: 10, TurnTimeoutAction: TimeoutActionForfeit})
+		game := newTestGame(t, p)
+		game.Players = []string{"user1", "bot", "user2"}
+		game.Scores = map[string]int{"user1": 0, "bot": 0, "user2": 0}
+		game.BotMemory = BotMemoryMedium
+		game.ActiveAt = 1
+		require.NoError(t, p.setGame(game))
+
+		require.NoError(t, p.checkGameTimeout(game, 1+minutes(10)))
+		game, err := p.getGame("game")
+		require.NoError(t, err)
+		assert.Equal(t, "bot", game.CurrentPlayer())
+		_, playing := p.botTurns.Load("game")
+		assert.True(t, playing)
+	})
+
+	t.Run("abandoned", func(t *testing.T) {
+		p := newTestPlugin(t)
+		p.setConfiguration(&configuration{AbandonedGameDays: 2})
+		api := p.API.(*fakeAPI)
+		game := newTestGame(t, p)
+		game.ActiveAt = 1
+		game.BoardPostID = "post"
+		require.NoError(t, p.setGame(game))
+		require.NoError(t, p.addActiveGame("game"))
+
+		require.NoError(t, p.checkGameTimeout(game, 1+minutes(24*60)))
+		_, err := p.getGame("game")
+		require.NoError(t, err)
+
+		require.NoError(t, p.checkGameTimeout(game, 1+minutes(2*24*60)))
+		_, err = p.getGame("game")
+		assert.ErrorIs(t, err, ErrGameNotFound)
+
+		gameIDs, err := p.getActiveGameIDs()
+		require.NoError(t, err)
+		assert.Empty(t, gameIDs)
+
+		require.Len(t, api.posts, 1)
+		assert.Equal(t, "post", api.posts[0].Id)
+		assert.Contains(t, api.posts[0].Message, "The game was removed after being abandoned.")
+		assert.Empty(t, api.posts[0].Attachments())
+	})
+}
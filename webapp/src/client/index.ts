@@ -6,6 +6,202 @@ import {ClientError} from 'mattermost-redux/client/client4';
 
 import manifest from '../manifest';
 
+export type GameState = {
+    cards: string[];
+    turn: boolean;
+    lastFlipped: number;
+    myScore: number;
+    rows: number;
+    columns: number;
+    seq: number;
+    started: boolean;
+    isOwner: boolean;
+    currentPlayer: string;
+    players: string[];
+    scores: number[];
+    drawOffers: string[];
+    solo: boolean;
+    moves: number;
+    createAt: number;
+    seedHash?: string;
+};
+
+export type VerifySeedResult = {
+    gID: string;
+    seed: string;
+    seedHash: string;
+    hashMatch: boolean;
+    cards: string[];
+    cardsMatch: boolean;
+};
+
+export type RatingChange = {
+    gID: string;
+    rating: number;
+    delta: number;
+    at: number;
+};
+
+export type Rating = {
+    userID: string;
+    username: string;
+    rating: number;
+    provisional: boolean;
+    games: number;
+    peak: number;
+    history: RatingChange[];
+};
+
+export type APIError = {
+    id: string;
+    message: string;
+    status_code: number;
+};
+
+export type FlipResult = {
+    value: string;
+    seq: number;
+    turn: boolean;
+    currentPlayer: string;
+    scores: number[];
+};
+
+export type StartGameResponse = {
+    gID: string;
+    turn: boolean;
+    invitationID?: string;
+};
+
+export type BoardFormat = 'markdown' | 'text';
+
+export type RenderedBoard = {
+    gID: string;
+    format: BoardFormat;
+    board: string;
+};
+
+export type MatchmakingResult = {
+    queued: boolean;
+    gID?: string;
+    channelID?: string;
+};
+
+export type Series = {
+    id: string;
+    players: string[];
+    wins: {[userID: string]: number};
+    draws: number;
+    games: number;
+};
+
+export type RematchResponse = {
+    gID: string;
+    turn: boolean;
+    series: Series | null;
+};
+
+export type GameSummary = {
+    gID: string;
+    started: boolean;
+    isPlayer: boolean;
+    currentPlayer: string;
+    players: string[];
+    rows: number;
+    columns: number;
+    solo: boolean;
+    daily?: string;
+};
+
+export type LeaderboardEntry = {
+    rank: number;
+    userID: string;
+    username: string;
+    wins: number;
+    losses: number;
+    draws: number;
+    gamesPlayed: number;
+    winRate: number;
+    bestWinStreak: number;
+};
+
+export type OpponentRecord = {
+    userID: string;
+    username: string;
+    wins: number;
+    losses: number;
+    draws: number;
+    gamesPlayed: number;
+};
+
+export type GameRecord = {
+    wins: number;
+    losses: number;
+    draws: number;
+    gamesPlayed: number;
+    winStreak: number;
+    bestWinStreak: number;
+};
+
+export type PlayerStats = {
+    userID: string;
+    username: string;
+    wins: number;
+    losses: number;
+    draws: number;
+    gamesPlayed: number;
+    winRate: number;
+    bestWinStreak: number;
+    pairsMatched: number;
+    longestMatchStreak: number;
+    averageMoves: number;
+    opponents: OpponentRecord[];
+    botGames: GameRecord;
+};
+
+export type SoloResult = {
+    gID: string;
+    moves: number;
+    duration: number;
+    endAt: number;
+};
+
+export type SoloLeaderboardEntry = SoloResult & {
+    rank: number;
+    userID: string;
+    username: string;
+};
+
+export type SoloLeaderboard = {
+    difficulty: string;
+    total: number;
+    entries: SoloLeaderboardEntry[];
+};
+
+export type SoloStats = {
+    userID: string;
+    username: string;
+    bests: {[difficulty: string]: SoloResult};
+};
+
+export type DailyChallenge = {
+    date: string;
+    difficulty: string;
+    total: number;
+    entries: SoloLeaderboardEntry[];
+    gID?: string;
+    result?: SoloResult;
+    rank?: number;
+    streak: number;
+    bestStreak: number;
+};
+
+export type Leaderboard = {
+    period: string;
+    teamID?: string;
+    total: number;
+    entries: LeaderboardEntry[];
+};
+
 export default class Client {
     private url: string;
 
@@ -13,30 +209,184 @@ export default class Client {
         this.url = '/plugins/' + manifest.id + '/api/v1';
     }
 
-    async flip(gID: string, index: string): Promise<string> {
+    async flip(gID: string, index: string, seq: number): Promise<FlipResult> {
         try {
-            const res = await this.doPost(`${this.url}/game/${gID}/flip`, {index});
-            return res.value as string;
+            const res = await this.doPost(`${this.url}/game/${gID}/flip`, {index, seq});
+            return res as FlipResult;
         } catch {
-            return '';
+            return {value: '', seq, turn: false, currentPlayer: '', scores: []};
         }
     }
 
-    async startGame(channelID: string): Promise<{gID: string, turn: boolean}> {
+    async startGame(channelID: string, difficulty = '', solo = false): Promise<StartGameResponse> {
         try {
-            const res = await this.doPost(`${this.url}/start`, {channelID});
-            return res as {gID: string, turn: boolean};
+            const res = await this.doPost(`${this.url}/start`, {channelID, difficulty, solo});
+            return res as StartGameResponse;
         } catch {
             return {gID: '', turn: false};
         }
     }
 
-    async getGame(gID: string): Promise<{cards: string[], turn: boolean, lastFlipped: number, opponentName: string, myScore: number, opponentScore: number}> {
+    async getGame(gID: string): Promise<GameState> {
         try {
             const res = await this.doGet(`${this.url}/game/${gID}`);
-            return res as {cards: string[], turn: boolean, lastFlipped: number, opponentName: string, myScore: number, opponentScore: number};
+            return res as GameState;
+        } catch {
+            return {cards: [], turn: false, lastFlipped: -1, myScore: 0, rows: 0, columns: 0, seq: 0, started: false, isOwner: false, currentPlayer: '', players: [], scores: [], drawOffers: []};
+        }
+    }
+
+    async resign(gID: string): Promise<boolean> {
+        return this.doGameAction(gID, 'resign');
+    }
+
+    async offerDraw(gID: string): Promise<boolean> {
+        return this.doGameAction(gID, 'offer-draw');
+    }
+
+    async acceptDraw(gID: string): Promise<boolean> {
+        return this.doGameAction(gID, 'accept-draw');
+    }
+
+    async rematch(gID: string): Promise<RematchResponse> {
+        try {
+            const res = await this.doPost(`${this.url}/game/${gID}/rematch`, {});
+            return res as RematchResponse;
+        } catch {
+            return {gID: '', turn: false, series: null};
+        }
+    }
+
+    private async doGameAction(gID: string, action: string): Promise<boolean> {
+        try {
+            await this.doPost(`${this.url}/game/${gID}/${action}`, {});
+            return true;
+        } catch {
+            return false;
+        }
+    }
+
+    async getChannelGames(channelID: string): Promise<GameSummary[]> {
+        try {
+            const res = await this.doGet(`${this.url}/channel/${channelID}/games`);
+            return res as GameSummary[];
+        } catch {
+            return [];
+        }
+    }
+
+    async getStats(userID: string): Promise<PlayerStats | null> {
+        try {
+            const res = await this.doGet(`${this.url}/stats/${userID}`);
+            return res as PlayerStats;
+        } catch {
+            return null;
+        }
+    }
+
+    async getRating(userID: string): Promise<Rating | null> {
+        try {
+            const res = await this.doGet(`${this.url}/ratings/${userID}`);
+            return res as Rating;
+        } catch {
+            return null;
+        }
+    }
+
+    async getLeaderboard(period = 'all', teamID = '', page = 0, perPage = 20): Promise<Leaderboard> {
+        const params = new URLSearchParams({period, page: String(page), per_page: String(perPage)});
+        if (teamID) {
+            params.set('team_id', teamID);
+        }
+
+        try {
+            const res = await this.doGet(`${this.url}/leaderboard?${params.toString()}`);
+            return res as Leaderboard;
+        } catch {
+            return {period, total: 0, entries: []};
+        }
+    }
+
+    async getSoloStats(userID: string): Promise<SoloStats | null> {
+        try {
+            const res = await this.doGet(`${this.url}/solo/stats/${userID}`);
+            return res as SoloStats;
+        } catch {
+            return null;
+        }
+    }
+
+    async getSoloLeaderboard(difficulty = 'easy', page = 0, perPage = 20): Promise<SoloLeaderboard> {
+        const params = new URLSearchParams({difficulty, page: String(page), per_page: String(perPage)});
+
+        try {
+            const res = await this.doGet(`${this.url}/solo/leaderboard?${params.toString()}`);
+            return res as SoloLeaderboard;
+        } catch {
+            return {difficulty, total: 0, entries: []};
+        }
+    }
+
+    async startDailyChallenge(): Promise<StartGameResponse> {
+        try {
+            const res = await this.doPost(`${this.url}/daily`, {});
+            return res as StartGameResponse;
+        } catch {
+            return {gID: '', turn: false};
+        }
+    }
+
+    async getDailyChallenge(date = '', page = 0, perPage = 20): Promise<DailyChallenge | null> {
+        const params = new URLSearchParams({page: String(page), per_page: String(perPage)});
+        if (date) {
+            params.set('date', date);
+        }
+
+        try {
+            const res = await this.doGet(`${this.url}/daily?${params.toString()}`);
+            return res as DailyChallenge;
         } catch {
-            return {cards: [], turn: false, lastFlipped: -1, opponentName: '', myScore: 0, opponentScore: 0};
+            return null;
+        }
+    }
+
+    async joinMatchmaking(difficulty = '', teamID = '', ratingWindow = 0): Promise<MatchmakingResult> {
+        try {
+            const res = await this.doPost(`${this.url}/matchmaking/join`, {difficulty, teamID, ratingWindow});
+            return res as MatchmakingResult;
+        } catch {
+            return {queued: false};
+        }
+    }
+
+    async leaveMatchmaking(): Promise<boolean> {
+        try {
+            await this.doPost(`${this.url}/matchmaking/leave`, {});
+            return true;
+        } catch {
+            return false;
+        }
+    }
+
+    async getBoard(gID: string, format: BoardFormat = 'markdown'): Promise<RenderedBoard | null> {
+        try {
+            const res = await this.doGet(`${this.url}/game/${gID}/board?format=${format}`);
+            return res as RenderedBoard;
+        } catch {
+            return null;
+        }
+    }
+
+    getSnapshotURL(gID: string): string {
+        return `${this.url}/game/${gID}/snapshot.png`;
+    }
+
+    async verifyGame(gID: string): Promise<VerifySeedResult | null> {
+        try {
+            const res = await this.doGet(`${this.url}/game/${gID}/verify`);
+            return res as VerifySeedResult;
+        } catch {
+            return null;
         }
     }
 
@@ -62,13 +412,7 @@ export default class Client {
             return response.json();
         }
 
-        const text = await response.text();
-
-        throw new ClientError(Client4.url, {
-            message: text || '',
-            status_code: response.status,
-            url,
-        });
+        throw await this.toClientError(url, response);
     }
 
     private doPost = async (url: string, body: any, headers: {[x:string]: string} = {}) => {
@@ -86,13 +430,7 @@ export default class Client {
             return response.json();
         }
 
-        const text = await response.text();
-
-        throw new ClientError(Client4.url, {
-            message: text || '',
-            status_code: response.status,
-            url,
-        });
+        throw await this.toClientError(url, response);
     }
 
     private doDelete = async (url: string, headers: {[x:string]: string} = {}) => {
@@ -109,13 +447,7 @@ export default class Client {
             return response.json();
         }
 
-        const text = await response.text();
-
-        throw new ClientError(Client4.url, {
-            message: text || '',
-            status_code: response.status,
-            url,
-        });
+        throw await this.toClientError(url, response);
     }
 
     private doPut = async (url: string, body: any, headers: {[x:string]: string} = {}) => {
@@ -133,10 +465,24 @@ export default class Client {
             return response.json();
         }
 
+        throw await this.toClientError(url, response);
+    }
+
+    // toClientError reads the APIErrorResponse sent by the server for a
+    // failed request.
+    private toClientError = async (url: string, response: Response) => {
         const text = await response.text();
 
-        throw new ClientError(Client4.url, {
-            message: text || '',
+        let apiError: APIError | null = null;
+        try {
+            apiError = JSON.parse(text) as APIError;
+        } catch {
+            // The body is not an API error
+        }
+
+        return new ClientError(Client4.url, {
+            message: apiError?.message || text || '',
+            server_error_id: apiError?.id,
             status_code: response.status,
             url,
         });
@@ -0,0 +1,104 @@
+import React from 'react';
+
+import {Post} from 'mattermost-redux/types/posts';
+
+import Client from 'client';
+
+export type ResultScore = {
+    userID: string;
+    username: string;
+    pairs: number;
+    moves: number;
+    streak: number;
+    resigned: boolean;
+};
+
+type Props = {
+    post: Post;
+}
+
+type State = {
+    rematchText: string;
+}
+
+// formatDuration formats a duration in milliseconds like 3m12s.
+function formatDuration(duration: number): string {
+    const seconds = Math.round(duration / 1000);
+    if (seconds < 60) {
+        return `${seconds}s`;
+    }
+
+    return `${Math.floor(seconds / 60)}m${seconds % 60}s`;
+}
+
+export default class ResultPost extends React.Component<Props, State> {
+    constructor(props: Props) {
+        super(props);
+
+        this.state = {rematchText: ''};
+    }
+
+    render() {
+        const props = this.props.post.props || {};
+        const scores: ResultScore[] = props.scores || [];
+        const username = (userID: string) => scores.find((score) => score.userID === userID)?.username || userID;
+
+        let status = 'The game is over.';
+        if (props.drawn) {
+            status = 'The game ended in a draw.';
+        } else if (props.winner) {
+            status = `@${username(props.winner)} won the game.`;
+        }
+
+        return (
+            <div>
+                <h4>{'Memory game over'}</h4>
+                <p>{status}</p>
+                <table className='table'>
+                    <thead>
+                        <tr>
+                            <th>{'Player'}</th>
+                            <th>{'Pairs'}</th>
+                            <th>{'Moves'}</th>
+                            <th>{'Best streak'}</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {scores.map((score) => (
+                            <tr key={score.userID}>
+                                <td>{`@${score.username}`}{score.resigned && ' (resigned)'}</td>
+                                <td>{score.pairs}</td>
+                                <td>{score.moves}</td>
+                                <td>{score.streak}</td>
+                            </tr>
+                        ))}
+                    </tbody>
+                </table>
+                <p>
+                    {`${props.moves} moves in ${formatDuration(props.duration)}.`}
+                    {props.longestStreak > 1 && ` Longest streak: ${props.longestStreak} pairs in a row by @${username(props.longestStreakUserID)}.`}
+                </p>
+                {!props.solo && (
+                    <button
+                        className='btn btn-primary'
+                        onClick={this.rematch}
+                    >
+                        {'Rematch'}
+                    </button>
+                )}
+                {this.state.rematchText && <p>{this.state.rematchText}</p>}
+            </div>
+        );
+    }
+
+    private rematch = () => {
+        const client = new Client();
+        client.rematch(this.props.post.props.gID).then((res) => {
+            if (!res.gID) {
+                this.setState({rematchText: 'Cannot start the rematch.'});
+                return;
+            }
+            this.setState({rematchText: 'Rematch started. Open the memory game to play.'});
+        });
+    }
+}
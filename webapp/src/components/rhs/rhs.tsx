@@ -3,25 +3,256 @@ import Phaser from 'phaser';
 
 import {Scene1} from 'phaser/scene1';
 import {canvasHeight, canvasWidth} from 'contants';
+import Client, {GameState, GameSummary} from 'client';
+import EventDispatcher from 'phaser/event_emitter';
 
 type Props = {
     currentChannelID: string
 }
-export default class App extends React.Component<Props> {
+
+type State = {
+    gID: string;
+    games: GameSummary[];
+    drawOffers: string[];
+}
+
+export default class App extends React.Component<Props, State> {
     private game: Phaser.Game| null
     constructor(props: Props) {
         super(props);
 
         this.game = null;
+        this.state = {gID: '', games: [], drawOffers: []};
     }
 
     componentDidMount() {
+        EventDispatcher.getInstance().on('resync', this.onResync);
+        EventDispatcher.getInstance().on('invitation_accepted', this.onGameCreated);
+        EventDispatcher.getInstance().on('rematch', this.onGameCreated);
+        EventDispatcher.getInstance().on('match_found', this.onGameCreated);
+        this.loadGames();
+    }
+
+    componentDidUpdate(prevProps: Props, prevState: State) {
+        if (prevProps.currentChannelID !== this.props.currentChannelID) {
+            this.selectGame('');
+            this.loadGames();
+            return;
+        }
+
+        if (prevState.gID !== this.state.gID && this.state.gID) {
+            this.startPhaser(this.state.gID);
+        }
+    }
+
+    componentWillUnmount() {
+        EventDispatcher.getInstance().off('resync', this.onResync);
+        EventDispatcher.getInstance().off('invitation_accepted', this.onGameCreated);
+        EventDispatcher.getInstance().off('rematch', this.onGameCreated);
+        EventDispatcher.getInstance().off('match_found', this.onGameCreated);
+        this.stopPhaser();
+    }
+
+    render() {
+        if (this.state.gID) {
+            const selected = this.state.games.find((game) => game.gID === this.state.gID);
+            return (
+                <div>
+                    <button
+                        className='btn btn-link'
+                        onClick={this.backToGames}
+                    >
+                        {'Back to the games of this channel'}
+                    </button>
+                    <section
+                        id='phaser-target'
+                        style={{textAlign: 'center'}}
+                    />
+                    {selected?.isPlayer && selected.started && this.renderGameActions(selected.solo)}
+                </div>
+            );
+        }
+
+        return (
+            <div style={{padding: '16px'}}>
+                {this.state.games.length === 0 && <p>{'There are no memory games in this channel.'}</p>}
+                {this.state.games.map((game) => (
+                    <div
+                        key={game.gID}
+                        style={{marginBottom: '12px'}}
+                    >
+                        <div>{this.describePlayers(game)}</div>
+                        <div>{game.started ? `@${game.currentPlayer}'s turn` : 'Waiting to begin'}</div>
+                        <button
+                            className='btn btn-primary'
+                            onClick={() => this.selectGame(game.gID)}
+                        >
+                            {game.isPlayer ? 'Resume' : 'Watch'}
+                        </button>
+                    </div>
+                ))}
+                <button
+                    className='btn btn-secondary'
+                    onClick={this.newGame}
+                >
+                    {'New game'}
+                </button>
+                <button
+                    className='btn btn-secondary'
+                    style={{marginLeft: '8px'}}
+                    onClick={this.newSoloGame}
+                >
+                    {'Practice alone'}
+                </button>
+                <button
+                    className='btn btn-secondary'
+                    style={{marginLeft: '8px'}}
+                    onClick={this.newDailyChallenge}
+                >
+                    {'Daily challenge'}
+                </button>
+            </div>
+        );
+    }
+
+    private describePlayers(game: GameSummary) {
+        if (game.daily) {
+            return `@${game.players[0]} playing the daily challenge of ${game.daily}`;
+        }
+        if (game.solo) {
+            return `@${game.players[0]} practicing alone`;
+        }
+        return game.players.map((player) => '@' + player).join(', ');
+    }
+
+    private renderGameActions(solo: boolean) {
+        const gID = this.state.gID;
+        const client = new Client();
+
+        if (solo) {
+            return (
+                <div style={{padding: '16px'}}>
+                    <button
+                        className='btn btn-danger'
+                        onClick={() => client.resign(gID).then(this.backToGames)}
+                    >
+                        {'Give up'}
+                    </button>
+                </div>
+            );
+        }
+
+        return (
+            <div style={{padding: '16px'}}>
+                {this.state.drawOffers.length > 0 && <p>{`Draw offered by ${this.state.drawOffers.map((player) => '@' + player).join(', ')}`}</p>}
+                {this.state.drawOffers.length === 0 ? (
+                    <button
+                        className='btn btn-secondary'
+                        onClick={() => client.offerDraw(gID)}
+                    >
+                        {'Offer draw'}
+                    </button>
+                ) : (
+                    <button
+                        className='btn btn-secondary'
+                        onClick={() => client.acceptDraw(gID)}
+                    >
+                        {'Accept draw'}
+                    </button>
+                )}
+                <button
+                    className='btn btn-danger'
+                    style={{marginLeft: '8px'}}
+                    onClick={() => client.resign(gID).then(this.backToGames)}
+                >
+                    {'Resign'}
+                </button>
+            </div>
+        );
+    }
+
+    private onResync = (state: GameState & {gID: string}) => {
+        if (state.gID === this.state.gID) {
+            this.setState({drawOffers: state.drawOffers || []});
+        }
+    }
+
+    private onGameCreated = (data: {gID: string, channelID: string, previousGID?: string}) => {
+        if (data.channelID !== this.props.currentChannelID) {
+            return;
+        }
+        this.loadGames();
+        if (!this.state.gID || this.state.gID === data.previousGID) {
+            this.selectGame(data.gID);
+        }
+    }
+
+    private backToGames = () => {
+        this.selectGame('');
+        this.loadGames();
+    }
+
+    private loadGames = () => {
+        const client = new Client();
+        client.getChannelGames(this.props.currentChannelID).then((games) => {
+            this.setState({games});
+            const playing = games.filter((game) => game.isPlayer);
+            if (!this.state.gID && playing.length === 1) {
+                this.selectGame(playing[0].gID);
+            }
+        });
+    }
+
+    private newGame = () => {
+        const client = new Client();
+        client.startGame(this.props.currentChannelID).then(({gID}) => {
+            if (gID) {
+                this.selectGame(gID);
+            }
+        });
+    }
+
+    private newSoloGame = () => {
+        const client = new Client();
+        client.startGame(this.props.currentChannelID, '', true).then(({gID}) => {
+            if (gID) {
+                this.loadGames();
+                this.selectGame(gID);
+            }
+        });
+    }
+
+    private newDailyChallenge = () => {
+        const client = new Client();
+        client.startDailyChallenge().then(({gID}) => {
+            if (gID) {
+                this.selectGame(gID);
+            }
+        });
+    }
+
+    private selectGame = (gID: string) => {
+        if (gID === this.state.gID) {
+            return;
+        }
+        this.stopPhaser();
+        this.setState({gID, drawOffers: []});
+        if (gID) {
+            new Client().getGame(gID).then((state) => {
+                if (this.state.gID === gID) {
+                    this.setState({drawOffers: state.drawOffers || []});
+                }
+            });
+        }
+    }
+
+    private startPhaser(gID: string) {
         const config: Phaser.Types.Core.GameConfig = {
             type: Phaser.AUTO,
             width: canvasWidth,
             height: canvasHeight,
             parent: 'phaser-target',
-            scene: this.extended(this.props.currentChannelID),
+            scene: this.extended(gID),
             backgroundColor: '#4a7957',
             loader: {
                 baseURL: '',
@@ -30,21 +261,13 @@ export default class App extends React.Component<Props> {
         this.game = new Phaser.Game(config);
     }
 
-    componentWillUnmount() {
+    private stopPhaser() {
         if (this.game) {
             this.game.destroy(true);
+            this.game = null;
         }
     }
 
-    render() {
-        return (
-            <section
-                id='phaser-target'
-                style={{textAlign: 'center'}}
-            />
-        );
-    }
-
     extended(gID: string) {
         function newConstructor(config: string | Phaser.Types.Scenes.SettingsConfig) {
             return new Scene1(config, gID);
@@ -6,6 +6,8 @@ export const margin = cardWidth * 0.1;
 export const boardMargin = 20 + 75;
 export const rows = 4;
 export const columns = 3;
+export const defaultRows = rows;
+export const defaultColumns = columns;
 export const canvasWidth = (cardWidth * columns) + (boardMargin * 2) + (margin * (columns - 1));
 export const canvasHeight = (cardHeight * rows) + (boardMargin * 2) + (margin * (rows - 1));
 export const ox = boardMargin + (cardWidth / 2);
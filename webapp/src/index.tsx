@@ -11,6 +11,7 @@ import {PluginRegistry} from 'types/mattermost-webapp';
 
 import RHS from 'components/rhs';
 import ChannelHeaderButton from 'components/channel_header_button';
+import ResultPost from 'components/result_post';
 import Client from 'client';
 import EventDispatcher from 'phaser/event_emitter';
 
@@ -21,6 +22,7 @@ export default class Plugin {
     public async initialize(registry: PluginRegistry, store: Store<GlobalState, GenericAction>) {
         // @see https://developers.mattermost.com/extend/plugins/webapp/reference/
         const {toggleRHSPlugin} = registry.registerRightHandSidebarComponent(RHS, 'Memory Game');
+        registry.registerPostTypeComponent('custom_memory_result', ResultPost);
 
         registry.registerWebSocketEventHandler(`custom_${manifest.id}_flip`, (msg:any) => {
             if (!msg.data) {
@@ -38,12 +40,44 @@ export default class Plugin {
             const ee = EventDispatcher.getInstance();
             ee.emit('resync', msg.data);
         });
+        registry.registerWebSocketEventHandler(`custom_${manifest.id}_invitation_accepted`, (msg:any) => {
+            if (!msg.data) {
+                return;
+            }
+
+            const ee = EventDispatcher.getInstance();
+            ee.emit('invitation_accepted', msg.data);
+        });
+        registry.registerWebSocketEventHandler(`custom_${manifest.id}_match_found`, (msg:any) => {
+            if (!msg.data) {
+                return;
+            }
+
+            const ee = EventDispatcher.getInstance();
+            ee.emit('match_found', msg.data);
+        });
+        registry.registerWebSocketEventHandler(`custom_${manifest.id}_rematch`, (msg:any) => {
+            if (!msg.data) {
+                return;
+            }
+
+            const ee = EventDispatcher.getInstance();
+            ee.emit('rematch', msg.data);
+        });
+        registry.registerWebSocketEventHandler(`custom_${manifest.id}_invitation_declined`, (msg:any) => {
+            if (!msg.data) {
+                return;
+            }
+
+            const ee = EventDispatcher.getInstance();
+            ee.emit('invitation_declined', msg.data);
+        });
 
         const openRHS = () => {
             const channelID = getCurrentChannelId(store.getState());
             const client = new Client();
-            client.getGame(channelID).then(({cards}) => {
-                if (cards.length === 0) {
+            client.getChannelGames(channelID).then((games) => {
+                if (games.length === 0) {
                     client.startGame(channelID).then(() => {
                         store.dispatch(toggleRHSPlugin);
                     });
@@ -1,9 +1,9 @@
 /* eslint-disable no-unused-expressions */
 import Phaser from 'phaser';
 
-import Client from 'client';
+import Client, {GameState} from 'client';
 
-import {canvasHeight, canvasWidth, cardHeight, cardScale, cardWidth, flipZoom, margin, ox, oy} from '../contants';
+import {boardMargin, canvasHeight, canvasWidth, cardHeight, cardScale, cardWidth, defaultColumns, defaultRows, margin} from '../contants';
 
 import {getAssetsURL} from 'utils';
 
@@ -16,8 +16,6 @@ export class Scene1 extends Phaser.Scene {
     }
 
     private cardsGroup: Phaser.GameObjects.Sprite[] = []
-    private myScore = 0;
-    private opponentScore = 0;
     private scoreText?: Phaser.GameObjects.Text;
     private turnText?: Phaser.GameObjects.Text;
     private ping?: Phaser.GameObjects.Container;
@@ -31,7 +29,19 @@ export class Scene1 extends Phaser.Scene {
     private loading = true;
     private gID;
 
-    private opponentUsername = '';
+    private started = false;
+    private currentPlayer = '';
+    private players: string[] = [];
+    private scores: number[] = [];
+
+    // pendingTurn holds the state sent by the server with the last flip,
+    // which is applied once the flip animation ends.
+    private pendingTurn?: {turn: boolean, currentPlayer: string, scores: number[]};
+
+    private cardsScale = cardScale;
+
+    // seq is the sequence number of the last move known to this client.
+    private seq = 0;
 
     preload() {
         this.load.setBaseURL(getAssetsURL());
@@ -39,49 +49,42 @@ export class Scene1 extends Phaser.Scene {
     }
 
     create() {
-        let index = 0;
-        for (let i = 0; i < 3; i++) {
-            for (let j = 0; j < 4; j++) {
-                this.cardsGroup.push(this.createCard(i, j, index));
-                index++;
-            }
-        }
-
         this.add.text(canvasWidth / 2, 50, 'MEMORY', {fontSize: '30px', align: 'center'}).setOrigin(0.5);
         this.turnText = this.add.text(canvasWidth / 2, canvasHeight - 30, '', {fontSize: '15px', align: 'center', wordWrap: {width: canvasWidth - margin}}).setOrigin(0.5);
-        this.scoreText = this.add.text(canvasWidth / 2, canvasHeight - 60, `Your score: ${this.myScore}\n@${this.opponentUsername}'s score: ${this.opponentScore}`, {fontSize: '15px', align: 'center'}).setOrigin(0.5);
+        this.scoreText = this.add.text(canvasWidth / 2, canvasHeight - 60, '', {fontSize: '15px', align: 'center'}).setOrigin(0.5);
         this.ping = this.pingButton();
 
         const client = new Client();
-        client.getGame(this.gID).then(({cards, turn, lastFlipped, opponentName, myScore, opponentScore}) => {
-            if (cards.length === 0) {
+        client.getGame(this.gID).then((state) => {
+            if (state.cards.length === 0) {
                 this.scoreText?.setText('');
-                this.turnText?.setText('Cannot get nor create a game for this channel. Try a DM.');
+                this.turnText?.setText('Cannot get nor create a game for this channel.');
                 return;
             }
-            this.opponentUsername = opponentName;
-            this.resync(cards, turn, lastFlipped, myScore, opponentScore);
+            this.resync(state);
             this.loading = false;
         });
 
         const ee = EventDispatcher.getInstance();
-        ee.on('remote_flip', ({index: cardIndex, value, gID}: any) => {
+        ee.on('remote_flip', ({index: cardIndex, value, gID, seq, turn, currentPlayer, scores}: any) => {
             if (gID !== this.gID) {
                 return;
             }
             if (this.finished) {
                 return;
             }
-            this.flip(this.cardsGroup[cardIndex], value, this.onRemoteFlipComplete);
+            this.seq = seq;
+            this.pendingTurn = {turn, currentPlayer, scores};
+            this.flip(this.cardsGroup[cardIndex], value, this.onFlipComplete);
         });
-        ee.on('resync', ({cards, turn, lastFlipped, gID, myScore, opponentScore}: {cards: string[], turn: boolean, lastFlipped: number, myScore: number, opponentScore: number, gID: string}) => {
-            if (this.gID !== gID) {
+        ee.on('resync', (state: GameState & {gID: string}) => {
+            if (this.gID !== state.gID) {
                 return;
             }
             if (this.finished) {
                 return;
             }
-            this.resync(cards, turn, lastFlipped, myScore, opponentScore);
+            this.resync(state);
         });
 
         this.events.on('destroy', () => {
@@ -89,10 +92,34 @@ export class Scene1 extends Phaser.Scene {
         });
     }
 
-    private createCard = (i: number, j: number, index: number) => {
-        const x = ox + ((cardWidth + margin) * i);
-        const y = oy + ((cardHeight + margin) * j);
-        const card = this.add.sprite(x, y, 'cards', 0).setInteractive().setScale(cardScale);
+    private createBoard = (rows: number, columns: number) => {
+        // Shrink the cards so bigger boards still fit in the canvas.
+        const fit = Math.min(
+            (canvasWidth - (boardMargin * 2)) / ((cardWidth + margin) * columns),
+            (canvasHeight - (boardMargin * 2)) / ((cardHeight + margin) * rows),
+            1,
+        );
+        this.cardsScale = cardScale * fit;
+
+        const width = ((cardWidth + margin) * fit * columns) - (margin * fit);
+        const height = ((cardHeight + margin) * fit * rows) - (margin * fit);
+        const ox = ((canvasWidth - width) / 2) + ((cardWidth * fit) / 2);
+        const oy = ((canvasHeight - height) / 2) + ((cardHeight * fit) / 2);
+
+        // Cards are laid out row by row, matching the server.
+        let index = 0;
+        for (let j = 0; j < rows; j++) {
+            for (let i = 0; i < columns; i++) {
+                const x = ox + ((cardWidth + margin) * fit * i);
+                const y = oy + ((cardHeight + margin) * fit * j);
+                this.cardsGroup.push(this.createCard(x, y, index));
+                index++;
+            }
+        }
+    }
+
+    private createCard = (x: number, y: number, index: number) => {
+        const card = this.add.sprite(x, y, 'cards', 0).setInteractive().setScale(this.cardsScale);
         card.setData('index', index);
         card.on('pointerup', () => {
             if (this.loading) {
@@ -103,7 +130,7 @@ export class Scene1 extends Phaser.Scene {
                 return;
             }
 
-            if (!this.myTurn) {
+            if (!this.started || !this.myTurn) {
                 return;
             }
 
@@ -119,9 +146,15 @@ export class Scene1 extends Phaser.Scene {
             const cardIndex = card.getData('index');
             const client = new Client();
             this.loading = true;
-            client.flip(this.gID, cardIndex).then((value) => {
-                this.flip(card, value, this.onUserFlipComplete);
+            client.flip(this.gID, cardIndex, this.seq).then(({value, seq, turn, currentPlayer, scores}) => {
                 this.loading = false;
+                if (!value) {
+                    // The server rejected the move and will send a resync.
+                    return;
+                }
+                this.seq = seq;
+                this.pendingTurn = {turn, currentPlayer, scores};
+                this.flip(card, value, this.onFlipComplete);
             });
         });
 
@@ -201,7 +234,7 @@ export class Scene1 extends Phaser.Scene {
         this.ping?.getData('disable')();
     }
 
-    private onUserFlipComplete = (gameObject: Phaser.GameObjects.GameObject) => {
+    private onFlipComplete = (gameObject: Phaser.GameObjects.GameObject) => {
         const currentCard = gameObject as Phaser.GameObjects.Sprite;
 
         if (this.firstFlipped === '') {
@@ -210,107 +243,85 @@ export class Scene1 extends Phaser.Scene {
             return;
         }
 
-        if (this.firstFlipped === currentCard.frame.name) {
-            this.myScore += 1;
-            this.firstFlipped = '';
-            this.firstFlippedIndex = -1;
-            this.scoreText?.setText(`Your score: ${this.myScore}\n@${this.opponentUsername}'s score: ${this.opponentScore}`);
-            let finished = true;
-            for (let i = 0; i < this.cardsGroup.length; i++) {
-                const card = this.cardsGroup[i];
-                const flipped = card.getData('flipped');
-                if (!flipped) {
-                    finished = false;
-                    break;
-                }
-            }
-
-            if (finished) {
-                this.finished = true;
-                this.turnText?.setText('The game has ended');
-                this.disablePingButton();
-            }
-            return;
+        if (this.firstFlipped !== currentCard.frame.name) {
+            const firstFlippedCard = this.cardsGroup[this.firstFlippedIndex];
+            this.flip(currentCard, 0);
+            this.flip(firstFlippedCard, 0);
         }
-
-        const firstFlippedCard = this.cardsGroup[this.firstFlippedIndex];
-        this.flip(currentCard, 0);
-        this.flip(firstFlippedCard, 0);
         this.firstFlipped = '';
         this.firstFlippedIndex = -1;
-        this.myTurn = false;
-        this.turnText?.setText(`It is @${this.opponentUsername}'s turn`);
-        this.enablePingButton();
-    }
-
-    private onRemoteFlipComplete = (gameObject: Phaser.GameObjects.GameObject) => {
-        const currentCard = gameObject as Phaser.GameObjects.Sprite;
 
-        if (this.firstFlipped === '') {
-            this.firstFlipped = currentCard.frame.name;
-            this.firstFlippedIndex = currentCard.getData('index');
-            return;
+        if (this.pendingTurn) {
+            this.myTurn = this.pendingTurn.turn;
+            this.currentPlayer = this.pendingTurn.currentPlayer;
+            this.scores = this.pendingTurn.scores;
+            this.pendingTurn = undefined;
         }
+        this.updateTexts();
 
-        if (this.firstFlipped === currentCard.frame.name) {
-            this.opponentScore += 1;
-            this.firstFlipped = '';
-            this.firstFlippedIndex = -1;
-            this.scoreText?.setText(`Your score: ${this.myScore}\n@${this.opponentUsername}'s score: ${this.opponentScore}`);
-
-            let finished = true;
-            for (let i = 0; i < this.cardsGroup.length; i++) {
-                const card = this.cardsGroup[i];
-                const flipped = card.getData('flipped');
-                if (!flipped) {
-                    finished = false;
-                    break;
-                }
-            }
-
-            if (finished) {
-                this.disablePingButton();
-                this.finished = true;
-                this.turnText?.setText('The game has ended');
+        let finished = true;
+        for (let i = 0; i < this.cardsGroup.length; i++) {
+            const card = this.cardsGroup[i];
+            const flipped = card.getData('flipped');
+            if (!flipped) {
+                finished = false;
+                break;
             }
-            return;
         }
 
-        const firstFlippedCard = this.cardsGroup[this.firstFlippedIndex];
-        this.flip(currentCard, 0);
-        this.flip(firstFlippedCard, 0);
-        this.firstFlipped = '';
-        this.firstFlippedIndex = -1;
-        this.myTurn = true;
-        this.turnText?.setText('It is your turn');
-        this.disablePingButton();
+        if (finished) {
+            this.finished = true;
+            this.turnText?.setText('The game has ended');
+            this.disablePingButton();
+        }
     }
 
-    resync(cards: string[], turn: boolean, firstFlippedIndex: number, myScore: number, opponentScore: number) {
-        this.firstFlippedIndex = firstFlippedIndex;
-        if (firstFlippedIndex === -1) {
-            this.firstFlipped = '';
-        } else {
-            this.firstFlipped = cards[firstFlippedIndex];
-        }
+    private updateTexts = () => {
+        const scores = this.players.map((player, i) => `@${player}: ${this.scores[i] || 0}`);
+        this.scoreText?.setText(scores.join('\n'));
+        this.scoreText?.setY(canvasHeight - 45 - (15 * this.players.length));
 
-        this.myScore = myScore;
-        this.opponentScore = opponentScore;
-        this.scoreText?.setText(`Your score: ${this.myScore}\n@${this.opponentUsername}'s score: ${this.opponentScore}`);
+        if (!this.started) {
+            this.turnText?.setText('Waiting for the game to begin. Use /memory join to play and /memory begin to start.');
+            this.disablePingButton();
+            return;
+        }
 
-        this.myTurn = turn;
-        if (turn) {
+        if (this.myTurn) {
             this.turnText?.setText('It is your turn');
             this.disablePingButton();
         } else {
-            this.turnText?.setText(`It is @${this.opponentUsername}'s turn`);
+            this.turnText?.setText(`It is @${this.currentPlayer}'s turn`);
             this.enablePingButton();
         }
+    }
+
+    resync(state: GameState) {
+        if (this.cardsGroup.length === 0) {
+            this.createBoard(state.rows || defaultRows, state.columns || defaultColumns);
+        }
+
+        this.seq = state.seq;
+        this.pendingTurn = undefined;
+
+        this.firstFlippedIndex = state.lastFlipped;
+        if (state.lastFlipped === -1) {
+            this.firstFlipped = '';
+        } else {
+            this.firstFlipped = state.cards[state.lastFlipped];
+        }
+
+        this.started = state.started;
+        this.myTurn = state.turn;
+        this.currentPlayer = state.currentPlayer;
+        this.players = state.players || [];
+        this.scores = state.scores || [];
+        this.updateTexts();
 
-        for (let i = 0; i < cards.length; i++) {
+        for (let i = 0; i < state.cards.length; i++) {
             const card = this.cardsGroup[i];
-            card.setFrame(cards[i]);
-            card.setData('flipped', cards[i] !== 'back');
+            card.setFrame(state.cards[i]);
+            card.setData('flipped', state.cards[i] !== 'back');
             card.setData('synced', true);
         }
     }
@@ -323,7 +334,7 @@ export class Scene1 extends Phaser.Scene {
             duration: 200,
             props: {
                 scaleX: 0,
-                scaleY: flipZoom,
+                scaleY: this.cardsScale * 1.2,
             },
             onComplete: (tween1, targets1) => {
                 const card1: Phaser.GameObjects.Sprite = targets1[0];
@@ -334,8 +345,8 @@ export class Scene1 extends Phaser.Scene {
                     targets: targets1,
                     duration: 200,
                     props: {
-                        scaleX: cardScale,
-                        scaleY: cardScale,
+                        scaleX: this.cardsScale,
+                        scaleY: this.cardsScale,
                     },
                     onComplete: (tween2, targets2) => {
                         const card2: Phaser.GameObjects.Sprite = targets2[0];